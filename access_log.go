@@ -0,0 +1,413 @@
+package ansilog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLogEntry is the typed access log of a request,
+// it is printed by the HttpTracer in the json and logfmt formats
+// and it is available in the Template as .Entry.
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Status    int       `json:"status"`
+	LatencyMs float64   `json:"latency_ms"`
+	Bytes     int       `json:"bytes"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Query     string    `json:"query,omitempty"`
+	RemoteIP  string    `json:"remote_ip"`
+	UserAgent string    `json:"user_agent"`
+	Referer   string    `json:"referer,omitempty"`
+	Proto     string    `json:"proto"`
+	User      string    `json:"user,omitempty"`
+
+	RequestBytes int64   `json:"request_bytes"`
+	ClientIP     string  `json:"client_ip"`
+	RequestID    string  `json:"request_id,omitempty"`
+	TraceID      string  `json:"trace_id,omitempty"`
+	SpanID       string  `json:"span_id,omitempty"`
+	Route        string  `json:"route,omitempty"`
+	TLSVersion   string  `json:"tls_version,omitempty"`
+	TLSCipher    string  `json:"tls_cipher,omitempty"`
+	TTFBMs       float64 `json:"ttfb_ms"`
+
+	// State is StateHijacked or StateStreamed for the connections
+	// hijacked or flushed by the handler, StateNoResponse if nothing was written.
+	State string `json:"state,omitempty"`
+
+	// Recovered reports whether the handler panicked, see WithRecovery.
+	Recovered bool `json:"recovered,omitempty"`
+}
+
+// requestTrace holds the request state collected by the middlewares.
+type requestTrace struct {
+	start     time.Time
+	firstByte time.Time
+	route     string
+	body      *countingBody
+	state     string
+	recovered bool
+}
+
+// newRequestTrace starts tracing r, its body is wrapped to count the request bytes.
+func newRequestTrace(r *http.Request) *requestTrace {
+	rt := &requestTrace{start: time.Now()}
+	if r.Body != nil && r.Body != http.NoBody {
+		rt.body = &countingBody{ReadCloser: r.Body}
+		r.Body = rt.body
+	}
+	return rt
+}
+
+// markFirstByte records the time the response headers are written.
+func (rt *requestTrace) markFirstByte() {
+	if rt.firstByte.IsZero() {
+		rt.firstByte = time.Now()
+	}
+}
+
+// ttfb returns the time to first byte, zero if nothing was written.
+func (rt *requestTrace) ttfb() time.Duration {
+	if rt.firstByte.IsZero() {
+		return 0
+	}
+	return rt.firstByte.Sub(rt.start)
+}
+
+// requestBytes returns the bytes read from the request body
+// or, if the body has not been read, its declared length.
+func (rt *requestTrace) requestBytes(r *http.Request) int64 {
+	if rt.body != nil && rt.body.size > 0 {
+		return rt.body.size
+	}
+	if r.ContentLength > 0 {
+		return r.ContentLength
+	}
+	return 0
+}
+
+// countingBody counts the bytes read from a request body.
+type countingBody struct {
+	io.ReadCloser
+	size int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	return n, err
+}
+
+func newAccessLogEntry(rw *responseWriter, r *http.Request, rt *requestTrace, latency time.Duration) AccessLogEntry {
+	entry := AccessLogEntry{
+		Time:         rt.start.UTC(),
+		Status:       rw.status,
+		LatencyMs:    milliseconds(latency),
+		Bytes:        rw.size,
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		Query:        r.URL.RawQuery,
+		RemoteIP:     remoteIP(r),
+		UserAgent:    r.UserAgent(),
+		Referer:      r.Referer(),
+		Proto:        r.Proto,
+		User:         requestUser(r),
+		RequestBytes: rt.requestBytes(r),
+		ClientIP:     clientIP(r),
+		RequestID:    requestID(rw, r),
+		Route:        rt.route,
+		TTFBMs:       milliseconds(rt.ttfb()),
+		State:        rt.state,
+		Recovered:    rt.recovered,
+	}
+	if entry.Status == 0 && len(entry.State) == 0 {
+		entry.State = StateNoResponse
+	}
+	if sc, ok := SpanContextFromContext(r.Context()); ok {
+		entry.TraceID, entry.SpanID = sc.TraceID, sc.SpanID
+	}
+	if r.TLS != nil {
+		entry.TLSVersion = tlsVersionName(r.TLS.Version)
+		entry.TLSCipher = tls.CipherSuiteName(r.TLS.CipherSuite)
+	}
+	return entry
+}
+
+// SentStatus returns the status sent to the client: net/http sends
+// 200 OK when the handler writes nothing, unless the connection was hijacked.
+func (e AccessLogEntry) SentStatus() int {
+	if e.Status == 0 && e.State != StateHijacked {
+		return http.StatusOK
+	}
+	return e.Status
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// pairs returns the entry fields in the json order,
+// empty optional fields are omitted.
+func (e AccessLogEntry) pairs() []kvPair {
+	pairs := []kvPair{
+		{"time", e.Time.Format(time.RFC3339Nano)},
+		{"status", e.Status},
+		{"latency_ms", e.LatencyMs},
+		{"bytes", e.Bytes},
+		{"method", e.Method},
+		{"path", e.Path},
+	}
+	if len(e.Query) > 0 {
+		pairs = append(pairs, kvPair{"query", e.Query})
+	}
+	pairs = append(pairs,
+		kvPair{"remote_ip", e.RemoteIP},
+		kvPair{"user_agent", e.UserAgent},
+	)
+	if len(e.Referer) > 0 {
+		pairs = append(pairs, kvPair{"referer", e.Referer})
+	}
+	pairs = append(pairs, kvPair{"proto", e.Proto})
+	if len(e.User) > 0 {
+		pairs = append(pairs, kvPair{"user", e.User})
+	}
+	pairs = append(pairs,
+		kvPair{"request_bytes", e.RequestBytes},
+		kvPair{"client_ip", e.ClientIP},
+	)
+	for _, pair := range []kvPair{
+		{"request_id", e.RequestID},
+		{"trace_id", e.TraceID},
+		{"span_id", e.SpanID},
+		{"route", e.Route},
+		{"tls_version", e.TLSVersion},
+		{"tls_cipher", e.TLSCipher},
+		{"state", e.State},
+	} {
+		if len(pair.value.(string)) > 0 {
+			pairs = append(pairs, pair)
+		}
+	}
+	pairs = append(pairs, kvPair{"ttfb_ms", e.TTFBMs})
+	if e.Recovered {
+		pairs = append(pairs, kvPair{"recovered", true})
+	}
+	return pairs
+}
+
+// Fields returns the entry as logrus fields.
+func (e AccessLogEntry) Fields() logrus.Fields {
+	return logEntry(e.pairs()).fields()
+}
+
+// JSON returns the entry as a json object.
+func (e AccessLogEntry) JSON() string {
+	return logEntry(e.pairs()).json()
+}
+
+// Logfmt returns the entry as space separated key=value pairs.
+func (e AccessLogEntry) Logfmt() string {
+	return logEntry(e.pairs()).logfmt()
+}
+
+// logEntry is a structured log record, its first pair is the time.
+type logEntry []kvPair
+
+// fields returns the pairs as logrus fields, the time is omitted
+// since it is added by the Logger itself.
+func (e logEntry) fields() logrus.Fields {
+	fields := logrus.Fields{}
+	for _, pair := range e[1:] {
+		fields[pair.key] = pair.value
+	}
+	return fields
+}
+
+// json returns the pairs as a json object, the pairs order is preserved.
+func (e logEntry) json() string {
+	b := &strings.Builder{}
+	b.WriteByte('{')
+	for i, pair := range e {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(jsonValue(pair.key) + ":" + jsonValue(pair.value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (e logEntry) logfmt() string {
+	parts := make([]string, 0, len(e))
+	for _, pair := range e {
+		parts = append(parts, pair.key+"="+formatValue(pair.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// requestUser returns the basic auth or the URL user name.
+func requestUser(r *http.Request) string {
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	if r.URL.User != nil {
+		return r.URL.User.Username()
+	}
+	return ""
+}
+
+// clientIP returns the first X-Forwarded-For address, the X-Real-IP header
+// or the request RemoteAddr host.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); len(forwarded) > 0 {
+		if ip := strings.TrimSpace(strings.Split(forwarded, ",")[0]); len(ip) > 0 {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); len(ip) > 0 {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// requestID returns the request id stored in the request context
+// or the X-Request-ID of the request or, if missing, of the response.
+func requestID(rw http.ResponseWriter, r *http.Request) string {
+	if id := RequestIDFromContext(r.Context()); len(id) > 0 {
+		return id
+	}
+	if id := r.Header.Get(echo.HeaderXRequestID); len(id) > 0 {
+		return id
+	}
+	return rw.Header().Get(echo.HeaderXRequestID)
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionSSL30: "SSL 3.0",
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsVersionName returns the name of a TLS version, eg.: "TLS 1.3".
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersions[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// remoteIP returns the host part of the request RemoteAddr.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Template funcs ------------------------------------------------------------------------------------------------------
+
+// templateFuncs are the funcs available in the HttpTracer templates:
+//
+//	dash    returns "-" for empty strings and zero numbers
+//	escape  escapes quotes, backslashes and non printable characters
+//	clfTime formats the time as in the Common Log Format: 10/Oct/2000:13:55:36 +0000
+//	w3c     returns "-" for empty strings and replaces spaces with "+"
+//	seconds converts milliseconds to seconds
+var templateFuncs = template.FuncMap{
+	"dash":    dash,
+	"escape":  escape,
+	"clfTime": clfTime,
+	"w3c":     w3cValue,
+	"seconds": func(ms float64) float64 { return ms / 1000 },
+}
+
+// ParseTemplate parses text as an HttpTracer template,
+// with the template funcs used by the built-in formats.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("ansilog_parser").Funcs(templateFuncs).Parse(text)
+}
+
+func dash(value interface{}) string {
+	s := fmt.Sprint(value)
+	if len(s) == 0 || s == "0" {
+		return "-"
+	}
+	return s
+}
+
+func escape(s string) string {
+	quoted := strconv.Quote(s)
+	return quoted[1 : len(quoted)-1]
+}
+
+func clfTime(t time.Time) string {
+	return t.Format("02/Jan/2006:15:04:05 -0700")
+}
+
+func w3cValue(value interface{}) string {
+	return strings.Replace(dash(value), " ", "+", -1)
+}
+
+// W3C Extended Log Format ---------------------------------------------------------------------------------------------
+
+// DefaultW3CFields are the W3C Extended Log Format fields used
+// when none is passed to W3CLogTemplate.
+var DefaultW3CFields = []string{
+	"date", "time", "c-ip", "cs-username", "cs-method", "cs-uri-stem", "cs-uri-query",
+	"sc-status", "sc-bytes", "time-taken", "cs(User-Agent)", "cs(Referer)",
+}
+
+// w3cFields maps the supported W3C fields to their template.
+var w3cFields = map[string]string{
+	"date":           `{{.Entry.Time.Format "2006-01-02"}}`,
+	"time":           `{{.Entry.Time.Format "15:04:05"}}`,
+	"c-ip":           `{{w3c .Entry.RemoteIP}}`,
+	"cs-username":    `{{w3c .Entry.User}}`,
+	"cs-method":      `{{w3c .Entry.Method}}`,
+	"cs-uri":         `{{w3c .RequestURI}}`,
+	"cs-uri-stem":    `{{w3c .Entry.Path}}`,
+	"cs-uri-query":   `{{w3c .Entry.Query}}`,
+	"cs-version":     `{{w3c .Entry.Proto}}`,
+	"sc-status":      `{{.Entry.SentStatus}}`,
+	"sc-bytes":       `{{.Entry.Bytes}}`,
+	"time-taken":     `{{printf "%.3f" (seconds .Entry.LatencyMs)}}`,
+	"cs(User-Agent)": `{{w3c .Entry.UserAgent}}`,
+	"cs(Referer)":    `{{w3c .Entry.Referer}}`,
+}
+
+// W3CLogTemplate returns the W3C Extended Log Format template
+// and its header directives for the given fields.
+// Supported fields are: date, time, c-ip, cs-username, cs-method, cs-uri,
+// cs-uri-stem, cs-uri-query, cs-version, sc-status, sc-bytes, time-taken,
+// cs(User-Agent) and cs(Referer), unknown fields are always "-".
+// Optional. Default fields are DefaultW3CFields.
+func W3CLogTemplate(fields ...string) (text string, header string) {
+	if len(fields) == 0 {
+		fields = DefaultW3CFields
+	}
+
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		value, ok := w3cFields[field]
+		if !ok {
+			value = "-"
+		}
+		values = append(values, value)
+	}
+
+	return strings.Join(values, " "), "#Version: 1.0\n#Fields: " + strings.Join(fields, " ")
+}
@@ -7,56 +7,293 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/mattn/go-isatty"
 )
 
+// ConsoleColorsModeEnum determine if colors must be used or not.
 type ConsoleColorsModeEnum int
 
 const (
+	// ConsoleColorsModeAuto use colors only if the output is a terminal,
+	// NO_COLOR, FORCE_COLOR, CLICOLOR and CLICOLOR_FORCE env vars are honored.
 	ConsoleColorsModeAuto ConsoleColorsModeEnum = iota
 	ConsoleColorsModeDisabled
 	ConsoleColorsModeEnabled
 )
 
+// Enabled reports whether colors must be used writing to out.
+func (m ConsoleColorsModeEnum) Enabled(out io.Writer) bool {
+	switch m {
+	case ConsoleColorsModeDisabled:
+		return false
+	case ConsoleColorsModeEnabled:
+		return true
+	}
+
+	// https://no-color.org
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return false
+	}
+
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		return force != "0" && strings.ToLower(force) != "false"
+	}
+
+	// https://bixense.com/clicolors
+	if force, ok := os.LookupEnv("CLICOLOR_FORCE"); ok && force != "0" {
+		return true
+	}
+	if os.Getenv("CLICOLOR") == "0" {
+		return false
+	}
+
+	return IsTerm(out)
+}
+
+// colorsCache caches the resolution of a ConsoleColorsModeEnum against a writer,
+// so that the env vars and the terminal are not checked for every painted string.
+// It is resolved again only when the mode or the writer change.
+type colorsCache struct {
+	mutex    sync.Mutex
+	resolved bool
+	mode     ConsoleColorsModeEnum
+	out      io.Writer
+	colors   bool
+}
+
+// enabled is like mode.Enabled(out), but cached.
+func (c *colorsCache) enabled(mode ConsoleColorsModeEnum, out io.Writer) bool {
+	// writers of non comparable types can't be compared to the cached one.
+	if out != nil && !reflect.TypeOf(out).Comparable() {
+		return mode.Enabled(out)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.resolved || c.mode != mode || c.out != out {
+		c.resolved, c.mode, c.out = true, mode, out
+		c.colors = mode.Enabled(out)
+	}
+	return c.colors
+}
+
+func (m ConsoleColorsModeEnum) String() string {
+	switch m {
+	case ConsoleColorsModeDisabled:
+		return "disabled"
+	case ConsoleColorsModeEnabled:
+		return "enabled"
+	default:
+		return "auto"
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (m ConsoleColorsModeEnum) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface,
+// so that the mode can be set from config files as
+// auto, disabled (never, off, false) or enabled (always, on, true).
+func (m *ConsoleColorsModeEnum) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "", "auto":
+		*m = ConsoleColorsModeAuto
+	case "disabled", "never", "off", "false", "no":
+		*m = ConsoleColorsModeDisabled
+	case "enabled", "always", "on", "true", "yes", "force":
+		*m = ConsoleColorsModeEnabled
+	default:
+		return fmt.Errorf("invalid colors mode: %s", text)
+	}
+	return nil
+}
+
 // Color ANSI codes ----------------------------------------------------------------------------------------------------
 
-type color string
+// Color is an ANSI SGR code, eg.: "31m" (red foreground).
+// Colors can be combined with styles in a single code, eg.: "1;31m" (bold red).
+type Color string
+
+// badge colors, a readable foreground on a background,
+// used by the BgX funcs and the KVLogger status badges.
+const (
+	badgeGreen   Color = "97;42m"
+	badgeWhite   Color = "90;47m"
+	badgeYellow  Color = "90;43m"
+	badgeRed     Color = "97;41m"
+	badgeBlue    Color = "97;44m"
+	badgeMagenta Color = "97;45m"
+	badgeCyan    Color = "97;46m"
+)
 
 const (
 	// from gin
-	defaultFG color = "39"
-
-	// background colors
-	bg_green   color = "97;42m"
-	bg_white   color = "90;47m"
-	bg_yellow  color = "90;43m"
-	bg_red     color = "97;41m"
-	bg_blue    color = "97;44m"
-	bg_magenta color = "97;45m"
-	bg_cyan    color = "97;46m"
-
-	white        color = "97m"
-	black        color = "30m"
-	red          color = "31m"
-	green        color = "32m"
-	yellow       color = "33m"
-	blue         color = "34m"
-	magenta      color = "35m"
-	cyan         color = "36m"
-	lightGrey    color = "37m"
-	darkGrey     color = "90m"
-	lightRed     color = "91m"
-	lightGreen   color = "92m"
-	lightYellow  color = "93m"
-	lightBlue    color = "94m"
-	lightMagenta color = "95m"
-	lightCyan    color = "96m"
+	ColorDefault Color = "39m"
+
+	// background colors, they can be combined with
+	// any foreground color, eg.: NewStyle().Foreground(ColorRed).Background(ColorBgWhite)
+	ColorBgGreen   Color = "42m"
+	ColorBgWhite   Color = "47m"
+	ColorBgYellow  Color = "43m"
+	ColorBgRed     Color = "41m"
+	ColorBgBlue    Color = "44m"
+	ColorBgMagenta Color = "45m"
+	ColorBgCyan    Color = "46m"
+
+	ColorWhite        Color = "97m"
+	ColorBlack        Color = "30m"
+	ColorRed          Color = "31m"
+	ColorGreen        Color = "32m"
+	ColorYellow       Color = "33m"
+	ColorBlue         Color = "34m"
+	ColorMagenta      Color = "35m"
+	ColorCyan         Color = "36m"
+	ColorLightGrey    Color = "37m"
+	ColorDarkGrey     Color = "90m"
+	ColorLightRed     Color = "91m"
+	ColorLightGreen   Color = "92m"
+	ColorLightYellow  Color = "93m"
+	ColorLightBlue    Color = "94m"
+	ColorLightMagenta Color = "95m"
+	ColorLightCyan    Color = "96m"
 
 	esc   = "\033["
 	clear = "\033[0m"
 )
 
+// colorNames map the normalized color and attribute names to their code.
+var colorNames = map[string]Color{
+	"default":      ColorDefault,
+	"white":        ColorWhite,
+	"black":        ColorBlack,
+	"red":          ColorRed,
+	"green":        ColorGreen,
+	"yellow":       ColorYellow,
+	"blue":         ColorBlue,
+	"magenta":      ColorMagenta,
+	"cyan":         ColorCyan,
+	"lightgrey":    ColorLightGrey,
+	"lightgray":    ColorLightGrey,
+	"darkgrey":     ColorDarkGrey,
+	"darkgray":     ColorDarkGrey,
+	"grey":         ColorDarkGrey,
+	"gray":         ColorDarkGrey,
+	"lightred":     ColorLightRed,
+	"lightgreen":   ColorLightGreen,
+	"lightyellow":  ColorLightYellow,
+	"lightblue":    ColorLightBlue,
+	"lightmagenta": ColorLightMagenta,
+	"lightcyan":    ColorLightCyan,
+
+	"bggreen":   ColorBgGreen,
+	"bgwhite":   ColorBgWhite,
+	"bgyellow":  ColorBgYellow,
+	"bgred":     ColorBgRed,
+	"bgblue":    ColorBgBlue,
+	"bgmagenta": ColorBgMagenta,
+	"bgcyan":    ColorBgCyan,
+
+	"bold":          Color("1m"),
+	"faint":         Color("2m"),
+	"dim":           Color("2m"),
+	"italic":        Color("3m"),
+	"underline":     Color("4m"),
+	"blink":         Color("5m"),
+	"reverse":       Color("7m"),
+	"conceal":       Color("8m"),
+	"hidden":        Color("8m"),
+	"strikethrough": Color("9m"),
+	"strike":        Color("9m"),
+}
+
+// ParseColor returns the Color from its name.
+// Names are case insensitive and dashes or underscores are ignored,
+// eg.: "red", "light-blue", "bg-yellow", "bold" or "#ff8800" ("bg#ff8800" for background).
+// Space separated names are combined, eg.: "bold light-red".
+// Raw SGR codes such as "34" or "38;5;208" are also accepted.
+// An empty name returns no color.
+func ParseColor(name string) (Color, error) {
+	var codes []string
+	for _, word := range strings.Fields(name) {
+		c, err := parseColorWord(word)
+		if err != nil {
+			return "", err
+		}
+		codes = append(codes, strings.TrimSuffix(string(c), "m"))
+	}
+
+	if len(codes) == 0 {
+		return "", nil
+	}
+	return Color(strings.Join(codes, ";") + "m"), nil
+}
+
+func parseColorWord(word string) (Color, error) {
+	normalized := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(word))
+
+	if c, ok := colorNames[normalized]; ok {
+		return c, nil
+	}
+
+	if strings.HasPrefix(normalized, "#") {
+		if _, _, _, err := parseHex(normalized); err == nil {
+			return Hex(normalized), nil
+		}
+	} else if strings.HasPrefix(normalized, "bg#") {
+		if _, _, _, err := parseHex(normalized[2:]); err == nil {
+			return BgHex(normalized[2:]), nil
+		}
+	} else if isSGR(strings.TrimSuffix(normalized, "m")) {
+		return Color(strings.TrimSuffix(normalized, "m") + "m"), nil
+	}
+
+	return "", fmt.Errorf("invalid color: %s", word)
+}
+
+// isSGR reports whether code is made of digits and semicolons only.
+func isSGR(code string) bool {
+	if len(code) == 0 {
+		return false
+	}
+	for _, r := range code {
+		if (r < '0' || r > '9') && r != ';' {
+			return false
+		}
+	}
+	return true
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface,
+// so that colors can be set by name from config files.
+func (c *Color) UnmarshalText(text []byte) error {
+	parsed, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Paint return the argument as a color escaped string.
+func (c Color) Paint(arg interface{}) string {
+	return colored(fmt.Sprint(arg), c)
+}
+
+// paint returns the argument as a color escaped string if colors is true.
+func paint(color Color, arg interface{}, colors bool) string {
+	if !colors {
+		return fmt.Sprint(arg)
+	}
+	return color.Paint(arg)
+}
+
 func IsTerm(out io.Writer) bool {
 	if w, ok := out.(*os.File); !ok || os.Getenv("TERM") == "dumb" ||
 		(!isatty.IsTerminal(w.Fd()) && !isatty.IsCygwinTerminal(w.Fd())) {
@@ -68,7 +305,7 @@ func IsTerm(out io.Writer) bool {
 type Painter func(interface{}) string
 
 // NewPainter is a PainterFunc which return a painter that can be stored and reused.
-func NewPainter(color color) Painter {
+func NewPainter(color Color) Painter {
 	return func(arg interface{}) string {
 		return colored(fmt.Sprint(arg), color)
 	}
@@ -76,7 +313,7 @@ func NewPainter(color color) Painter {
 
 // NewDynamicPainter is a PainterFunc which return a painter that can be stored and reused.
 // It also takes a func to determine if it must use colors or not.
-func NewDynamicPainter(color color, mustPaint func() bool) Painter {
+func NewDynamicPainter(color Color, mustPaint func() bool) Painter {
 	return func(arg interface{}) string {
 		if mustPaint != nil {
 			if !mustPaint() {
@@ -89,87 +326,125 @@ func NewDynamicPainter(color color, mustPaint func() bool) Painter {
 
 // Black return the argument as a color escaped string
 func Black(arg interface{}) string {
-	return colored(fmt.Sprint(arg), black)
+	return colored(fmt.Sprint(arg), ColorBlack)
 }
 
 // Red return the argument as a color escaped string
 func Red(arg interface{}) string {
-	return colored(fmt.Sprint(arg), red)
+	return colored(fmt.Sprint(arg), ColorRed)
 }
 
 // Green return the argument as a color escaped string
 func Green(arg interface{}) string {
-	return colored(fmt.Sprint(arg), green)
+	return colored(fmt.Sprint(arg), ColorGreen)
 }
 
 // Yellow return the argument as a color escaped string
 func Yellow(arg interface{}) string {
-	return colored(fmt.Sprint(arg), yellow)
+	return colored(fmt.Sprint(arg), ColorYellow)
 }
 
 // Blue return the argument as a color escaped string
 func Blue(arg interface{}) string {
-	return colored(fmt.Sprint(arg), blue)
+	return colored(fmt.Sprint(arg), ColorBlue)
 }
 
 // Magenta return the argument as a color escaped string
 func Magenta(arg interface{}) string {
-	return colored(fmt.Sprint(arg), magenta)
+	return colored(fmt.Sprint(arg), ColorMagenta)
 }
 
 // Cyan return the argument as a color escaped string
 func Cyan(arg interface{}) string {
-	return colored(fmt.Sprint(arg), cyan)
+	return colored(fmt.Sprint(arg), ColorCyan)
 }
 
 // LightGrey return the argument as a color escaped string
 func LightGrey(arg interface{}) string {
-	return colored(fmt.Sprint(arg), lightGrey)
+	return colored(fmt.Sprint(arg), ColorLightGrey)
 }
 
 // DarkGrey return the argument as a color escaped string
 func DarkGrey(arg interface{}) string {
-	return colored(fmt.Sprint(arg), darkGrey)
+	return colored(fmt.Sprint(arg), ColorDarkGrey)
 }
 
 // LightRed return the argument as a color escaped string
 func LightRed(arg interface{}) string {
-	return colored(fmt.Sprint(arg), lightRed)
+	return colored(fmt.Sprint(arg), ColorLightRed)
 }
 
 // LightGreen return the argument as a color escaped string
 func LightGreen(arg interface{}) string {
-	return colored(fmt.Sprint(arg), lightGreen)
+	return colored(fmt.Sprint(arg), ColorLightGreen)
 }
 
 // LightYellow return the argument as a color escaped string
 func LightYellow(arg interface{}) string {
-	return colored(fmt.Sprint(arg), lightYellow)
+	return colored(fmt.Sprint(arg), ColorLightYellow)
 }
 
 // LightBlue return the argument as a color escaped string
 func LightBlue(arg interface{}) string {
-	return colored(fmt.Sprint(arg), lightBlue)
+	return colored(fmt.Sprint(arg), ColorLightBlue)
 }
 
 // LightMagenta return the argument as a color escaped string
 func LightMagenta(arg interface{}) string {
-	return colored(fmt.Sprint(arg), lightMagenta)
+	return colored(fmt.Sprint(arg), ColorLightMagenta)
 }
 
 // LightCyan return the argument as a color escaped string
 func LightCyan(arg interface{}) string {
-	return colored(fmt.Sprint(arg), lightCyan)
+	return colored(fmt.Sprint(arg), ColorLightCyan)
 }
 
 // White return the argument as a color escaped string
 func White(arg interface{}) string {
-	return colored(fmt.Sprint(arg), white)
+	return colored(fmt.Sprint(arg), ColorWhite)
+}
+
+// BgGreen return the argument as a color escaped string
+func BgGreen(arg interface{}) string {
+	return colored(fmt.Sprint(arg), badgeGreen)
+}
+
+// BgWhite return the argument as a color escaped string
+func BgWhite(arg interface{}) string {
+	return colored(fmt.Sprint(arg), badgeWhite)
+}
+
+// BgYellow return the argument as a color escaped string
+func BgYellow(arg interface{}) string {
+	return colored(fmt.Sprint(arg), badgeYellow)
+}
+
+// BgRed return the argument as a color escaped string
+func BgRed(arg interface{}) string {
+	return colored(fmt.Sprint(arg), badgeRed)
+}
+
+// BgBlue return the argument as a color escaped string
+func BgBlue(arg interface{}) string {
+	return colored(fmt.Sprint(arg), badgeBlue)
+}
+
+// BgMagenta return the argument as a color escaped string
+func BgMagenta(arg interface{}) string {
+	return colored(fmt.Sprint(arg), badgeMagenta)
+}
+
+// BgCyan return the argument as a color escaped string
+func BgCyan(arg interface{}) string {
+	return colored(fmt.Sprint(arg), badgeCyan)
 }
 
 // colored return the ANSI colored string.
-func colored(arg string, color color) string {
+// Resets of nested colored strings are followed by the outer color,
+// so that the inner reset does not clear the outer style.
+func colored(arg string, color Color) string {
 	if len(color) > 0 {
+		arg = strings.Replace(arg, clear, clear+esc+string(color), -1)
 		return fmt.Sprintf(esc+"%s%s"+clear, color, arg)
 	}
 	return fmt.Sprintf("%s", arg)
@@ -1,7 +1,9 @@
 package ansilog
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"testing"
 )
 
@@ -29,3 +31,139 @@ func Test_ansilog(t *testing.T) {
 	fmt.Println(DarkGrey("DarkGrey"))
 	fmt.Println(Black("Black"))
 }
+
+// clearColorsEnv unsets the env vars honored by ConsoleColorsModeAuto,
+// the returned func restores them.
+func clearColorsEnv() (restore func()) {
+	var restores []func()
+	for _, key := range []string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR", "CLICOLOR_FORCE"} {
+		key := key
+		if value, ok := os.LookupEnv(key); ok {
+			restores = append(restores, func() { os.Setenv(key, value) })
+		} else {
+			restores = append(restores, func() { os.Unsetenv(key) })
+		}
+		os.Unsetenv(key)
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+}
+
+func TestConsoleColorsModeEnum_Enabled(t *testing.T) {
+	defer clearColorsEnv()()
+
+	out := &bytes.Buffer{}
+
+	if !ConsoleColorsModeEnabled.Enabled(out) {
+		t.Error("enabled mode must always use colors")
+	}
+	if ConsoleColorsModeDisabled.Enabled(os.Stdout) {
+		t.Error("disabled mode must never use colors")
+	}
+	if ConsoleColorsModeAuto.Enabled(out) {
+		t.Error("auto mode must not use colors on a non-terminal writer")
+	}
+
+	os.Setenv("FORCE_COLOR", "1")
+	if !ConsoleColorsModeAuto.Enabled(out) {
+		t.Error("FORCE_COLOR must enable colors")
+	}
+
+	os.Setenv("NO_COLOR", "1")
+	if ConsoleColorsModeAuto.Enabled(out) {
+		t.Error("NO_COLOR must take precedence over FORCE_COLOR")
+	}
+}
+
+func TestColorsCache(t *testing.T) {
+	defer clearColorsEnv()()
+	os.Setenv("FORCE_COLOR", "1")
+
+	cache := &colorsCache{}
+	out := &bytes.Buffer{}
+	if !cache.enabled(ConsoleColorsModeAuto, out) {
+		t.Fatal("FORCE_COLOR must enable colors")
+	}
+
+	os.Setenv("FORCE_COLOR", "0")
+	if !cache.enabled(ConsoleColorsModeAuto, out) {
+		t.Error("the mode must be resolved once per writer")
+	}
+	if cache.enabled(ConsoleColorsModeAuto, &bytes.Buffer{}) {
+		t.Error("the mode must be resolved again for a new writer")
+	}
+	if !cache.enabled(ConsoleColorsModeEnabled, out) {
+		t.Error("the mode must be resolved again when it changes")
+	}
+}
+
+func TestColorProfileDowngrade(t *testing.T) {
+	tests := []struct {
+		profile ColorProfileEnum
+		want    Color
+	}{
+		{ColorProfileTrueColor, "38;2;255;136;0m"},
+		{ColorProfile256, "38;5;208m"},
+		{ColorProfileBasic, "33m"},
+	}
+
+	for _, tt := range tests {
+		if got := rgbColor(0xff, 0x88, 0x00, false, tt.profile); got != tt.want {
+			t.Errorf("profile %d: got %q, want %q", tt.profile, got, tt.want)
+		}
+	}
+
+	if got := paletteColor(196, true, ColorProfileBasic); got != "101m" {
+		t.Errorf("palette 196 on basic profile: got %q, want %q", got, "101m")
+	}
+}
+
+func TestStyle(t *testing.T) {
+	style := NewStyle().Foreground(ColorRed).Background(BgPalette(4)).Bold().Underline()
+	if got, want := style.Paint("x"), "\033[1;4;31;44mx\033[0m"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, bg := range []Color{ColorBgGreen, ColorBgWhite, ColorBgYellow, ColorBgRed, ColorBgBlue, ColorBgMagenta, ColorBgCyan} {
+		want := Color("31;" + string(bg))
+		if got := NewStyle().Foreground(ColorRed).Background(bg).Color(); got != want {
+			t.Errorf("Foreground(ColorRed).Background(%q): got %q, want %q", bg, got, want)
+		}
+	}
+
+	nested := Blue("a " + Red("b") + " c")
+	if want := "\033[34ma \033[31mb\033[0m\033[34m c\033[0m"; nested != want {
+		t.Errorf("nested: got %q, want %q", nested, want)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Color
+		wantErr bool
+	}{
+		{"", "", false},
+		{"red", ColorRed, false},
+		{"Light-Blue", ColorLightBlue, false},
+		{"bg_yellow", ColorBgYellow, false},
+		{"red bg-red", "31;41m", false},
+		{"bold red", "1;31m", false},
+		{"34", "34m", false},
+		{"38;5;208", "38;5;208m", false},
+		{"purple", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColor(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: unexpected error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
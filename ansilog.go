@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"time"
+	"strings"
 
 	_ "github.com/lib/pq"
 	"github.com/oblq/ansilog/internal/hooks/pghook"
@@ -27,6 +27,23 @@ type Config struct {
 	// panic, fatal, error, warn, warning, info, debug and trace.
 	Level string
 
+	// Formatter is the output formatter config,
+	// the formatter can be selected by name: text, json, logfmt
+	// or any custom formatter registered with RegisterFormatter.
+	// Optional. Default value is text.
+	Formatter FormatterConfig
+
+	// ColorsMode determine if colors must be used by the formatter.
+	// Allowed values are: auto, disabled and enabled.
+	// Optional. Default value is auto, colors are used
+	// only if Out is a terminal.
+	ColorsMode ConsoleColorsModeEnum
+
+	// Theme is the name of a built-in theme (dark, light, solarized, monochrome),
+	// of a theme registered with RegisterTheme or the path of a YAML theme file.
+	// Optional. Default value is DefaultTheme.
+	Theme string
+
 	// StackTrace will extract stack-trace from errors created
 	// with "github.com/pkg/errors" package
 	// using Wrap() or WithStack() funcs.
@@ -46,6 +63,9 @@ type Config struct {
 
 type Logger struct {
 	*logrus.Logger
+
+	// colors reports whether the formatter uses colors.
+	colors bool
 }
 
 func NewWithConfig(config Config) (logger *Logger, err error) {
@@ -96,32 +116,23 @@ func (l *Logger) setup(config Config) error {
 		level = logrus.InfoLevel
 	}
 	l.Logger.Level = level
-	// config.Level
-	//log.Formatter = &logrus.JSONFormatter{
-	//	time.RFC3339,
-	//	false,
-	//	nil,
-	//}
-
-	l.Formatter = &logrus.TextFormatter{
-		ForceColors:            true,
-		DisableTimestamp:       false,
-		FullTimestamp:          true,
-		TimestampFormat:        time.RFC3339, //"2006-01-02 15:04:05", // time.RFC3339, // //"2006-01-02 15:04 Z07:00", 2006-01-02T15:04:05-0700
-		DisableSorting:         false,
-		DisableLevelTruncation: true,
-		QuoteEmptyFields:       true,
+
+	theme, err := resolveTheme(config.Theme)
+	if err != nil {
+		return fmt.Errorf("[logger] %v", err)
+	}
+
+	config.Formatter.Colors = config.ColorsMode.Enabled(l.Out)
+	config.Formatter.Theme = theme
+	formatter, err := NewFormatter(config.Formatter)
+	if err != nil {
+		return err
 	}
+	l.Formatter = formatter
+	l.colors = config.Formatter.Colors &&
+		(len(config.Formatter.Name) == 0 || strings.ToLower(config.Formatter.Name) == FormatterText)
 
-	//l.Formatter = &logrus.JSONFormatter{
-	//	TimestampFormat:   "2006-01-02 15:04:05",
-	//	DisableTimestamp:  false,
-	//	DisableHTMLEscape: false,
-	//	DataKey:           "",
-	//	FieldMap:          nil,
-	//	CallerPrettyfier:  nil,
-	//	PrettyPrint:       true,
-	//}
+	l.AddHook(contextHook{})
 
 	if config.StackTrace {
 		l.AddHook(stack_trace.New())
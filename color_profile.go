@@ -0,0 +1,200 @@
+package ansilog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorProfileEnum is the set of colors supported by the terminal.
+type ColorProfileEnum int
+
+const (
+	// ColorProfileBasic supports the 16 basic ANSI colors.
+	ColorProfileBasic ColorProfileEnum = iota
+	// ColorProfile256 supports the xterm 256 colors palette.
+	ColorProfile256
+	// ColorProfileTrueColor supports 24-bit RGB colors.
+	ColorProfileTrueColor
+)
+
+// DetectColorProfile returns the color profile of the current terminal
+// looking at the COLORTERM and TERM env vars.
+func DetectColorProfile() ColorProfileEnum {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorProfileTrueColor
+	}
+
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case strings.Contains(term, "truecolor"), strings.Contains(term, "24bit"),
+		strings.Contains(term, "direct"):
+		return ColorProfileTrueColor
+	case strings.Contains(term, "256"):
+		return ColorProfile256
+	}
+
+	return ColorProfileBasic
+}
+
+// Palette returns the foreground color at index n of the 256 colors palette,
+// downgraded to the nearest basic color if the terminal does not support it.
+func Palette(n uint8) Color {
+	return paletteColor(n, false, DetectColorProfile())
+}
+
+// BgPalette returns the background color at index n of the 256 colors palette,
+// downgraded to the nearest basic color if the terminal does not support it.
+func BgPalette(n uint8) Color {
+	return paletteColor(n, true, DetectColorProfile())
+}
+
+// RGB returns the 24-bit foreground color,
+// downgraded to the nearest supported color if needed.
+func RGB(r, g, b uint8) Color {
+	return rgbColor(r, g, b, false, DetectColorProfile())
+}
+
+// BgRGB returns the 24-bit background color,
+// downgraded to the nearest supported color if needed.
+func BgRGB(r, g, b uint8) Color {
+	return rgbColor(r, g, b, true, DetectColorProfile())
+}
+
+// Hex returns the foreground color from an hex string
+// such as "#ff8800" or "f80", downgraded to the nearest supported color if needed.
+// An invalid hex string returns no color.
+func Hex(hex string) Color {
+	r, g, b, err := parseHex(hex)
+	if err != nil {
+		return ""
+	}
+	return RGB(r, g, b)
+}
+
+// BgHex returns the background color from an hex string
+// such as "#ff8800" or "f80", downgraded to the nearest supported color if needed.
+// An invalid hex string returns no color.
+func BgHex(hex string) Color {
+	r, g, b, err := parseHex(hex)
+	if err != nil {
+		return ""
+	}
+	return BgRGB(r, g, b)
+}
+
+func paletteColor(n uint8, bg bool, profile ColorProfileEnum) Color {
+	if profile == ColorProfileBasic && n > 15 {
+		r, g, b := paletteToRGB(n)
+		n = nearestBasic(r, g, b)
+	}
+
+	if n < 16 {
+		return basicColor(n, bg)
+	}
+
+	if bg {
+		return Color(fmt.Sprintf("48;5;%dm", n))
+	}
+	return Color(fmt.Sprintf("38;5;%dm", n))
+}
+
+func rgbColor(r, g, b uint8, bg bool, profile ColorProfileEnum) Color {
+	switch profile {
+	case ColorProfileTrueColor:
+		if bg {
+			return Color(fmt.Sprintf("48;2;%d;%d;%dm", r, g, b))
+		}
+		return Color(fmt.Sprintf("38;2;%d;%d;%dm", r, g, b))
+	case ColorProfile256:
+		return paletteColor(nearest256(r, g, b), bg, profile)
+	default:
+		return basicColor(nearestBasic(r, g, b), bg)
+	}
+}
+
+// basicColor returns the SGR code of the basic color n (0-15).
+func basicColor(n uint8, bg bool) Color {
+	code := 30 + int(n)
+	if n > 7 {
+		code = 90 + int(n) - 8
+	}
+	if bg {
+		code += 10
+	}
+	return Color(strconv.Itoa(code) + "m")
+}
+
+// basicRGB are the xterm default values of the 16 basic colors.
+var basicRGB = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels are the values of each component in the 6x6x6 colors cube.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+func paletteToRGB(n uint8) (r, g, b uint8) {
+	switch {
+	case n < 16:
+		c := basicRGB[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[(n/6)%6], cubeLevels[n%6]
+	default:
+		gray := 8 + (n-232)*10
+		return gray, gray, gray
+	}
+}
+
+func nearest256(r, g, b uint8) uint8 {
+	best, bestDistance := uint8(16), -1
+	// skip the basic colors, they can be redefined by the terminal theme.
+	for n := 16; n < 256; n++ {
+		pr, pg, pb := paletteToRGB(uint8(n))
+		if d := distance(r, g, b, pr, pg, pb); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = uint8(n), d
+		}
+	}
+	return best
+}
+
+func nearestBasic(r, g, b uint8) uint8 {
+	best, bestDistance := uint8(0), -1
+	for n, c := range basicRGB {
+		if d := distance(r, g, b, c[0], c[1], c[2]); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = uint8(n), d
+		}
+	}
+	return best
+}
+
+// distance is the squared euclidean distance between two colors,
+// weighted on the human eye sensitivity.
+func distance(r1, g1, b1, r2, g2, b2 uint8) int {
+	dr := int(r1) - int(r2)
+	dg := int(g1) - int(g2)
+	db := int(b1) - int(b2)
+	return 3*dr*dr + 4*dg*dg + 2*db*db
+}
+
+func parseHex(hex string) (r, g, b uint8, err error) {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("invalid hex color: %s", hex)
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hex color: %s", hex)
+	}
+	return uint8(value >> 16), uint8(value >> 8), uint8(value), nil
+}
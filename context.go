@@ -0,0 +1,69 @@
+package ansilog
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type contextKey int
+
+const (
+	requestIDContextKey contextKey = iota
+	spanContextContextKey
+)
+
+// ContextWithRequestID returns a copy of ctx carrying the request id.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestIDFromContext returns the request id stored in ctx by the HttpTracer
+// middlewares, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// contextHook adds the ids stored in the entry context to the entry fields,
+// so that entries logged with WithContext(r.Context())
+// can be correlated with the access logs.
+type contextHook struct{}
+
+// Levels provide the levels to be logged.
+func (hook contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the context fields to the entry.
+func (hook contextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	fields := logrus.Fields{}
+	if id := RequestIDFromContext(entry.Context); len(id) > 0 {
+		fields["request_id"] = id
+	}
+	if sc, ok := SpanContextFromContext(entry.Context); ok {
+		fields["trace_id"] = sc.TraceID
+		fields["span_id"] = sc.SpanID
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	// entry.Data is shared with the parent entry, copy it before adding the fields
+	data := make(logrus.Fields, len(entry.Data)+len(fields))
+	for k, v := range fields {
+		data[k] = v
+	}
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	entry.Data = data
+	return nil
+}
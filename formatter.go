@@ -0,0 +1,286 @@
+package ansilog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Built-in formatter names.
+const (
+	FormatterText   = "text"
+	FormatterJSON   = "json"
+	FormatterLogfmt = "logfmt"
+)
+
+// FormatterConfig describes the output formatter used by the Logger.
+type FormatterConfig struct {
+	// Name is the formatter name: text, json, logfmt
+	// or any custom formatter registered with RegisterFormatter.
+	// Optional. Default value is text.
+	Name string `yaml:"name"`
+
+	// TimestampFormat is the layout used to print timestamps.
+	// Optional. Default value is time.RFC3339.
+	TimestampFormat string `yaml:"timestamp_format"`
+
+	// DisableTimestamp removes the timestamp from the output,
+	// useful when the log collector already adds it.
+	DisableTimestamp bool `yaml:"disable_timestamp"`
+
+	// FieldMap renames the default fields,
+	// eg.: {time: "@timestamp", level: "@level", msg: "@message"}.
+	// Allowed keys are: time, level, msg, logrus_error, func and file.
+	FieldMap map[string]string `yaml:"field_map"`
+
+	// DataKey nests all the entry fields under the given key (json only).
+	DataKey string `yaml:"data_key"`
+
+	// PrettyPrint indents the json output (json only).
+	PrettyPrint bool `yaml:"pretty_print"`
+
+	// Options holds any additional option for custom formatters.
+	Options map[string]interface{} `yaml:"options"`
+
+	// Colors is set by the Logger, resolving Config.ColorsMode
+	// against the Config.Out writer.
+	Colors bool `yaml:"-"`
+
+	// Theme is set by the Logger from Config.Theme,
+	// it is used by the text formatter when colors are enabled.
+	Theme *Theme `yaml:"-"`
+}
+
+// FormatterFactory build a logrus.Formatter from its config.
+type FormatterFactory func(config FormatterConfig) (logrus.Formatter, error)
+
+var (
+	formattersMutex sync.RWMutex
+	formatters      = map[string]FormatterFactory{
+		FormatterText:   newTextFormatter,
+		FormatterJSON:   newJSONFormatter,
+		FormatterLogfmt: newLogfmtFormatter,
+	}
+)
+
+// RegisterFormatter makes a custom formatter available by name,
+// so that it can be selected from Config.Formatter.Name.
+// Registering a built-in name will override it.
+func RegisterFormatter(name string, factory FormatterFactory) {
+	if factory == nil {
+		panic("ansilog: RegisterFormatter factory is nil")
+	}
+
+	formattersMutex.Lock()
+	defer formattersMutex.Unlock()
+	formatters[strings.ToLower(name)] = factory
+}
+
+// NewFormatter returns the formatter described by config.
+func NewFormatter(config FormatterConfig) (logrus.Formatter, error) {
+	name := strings.ToLower(config.Name)
+	if len(name) == 0 {
+		name = FormatterText
+	}
+
+	formattersMutex.RLock()
+	factory, ok := formatters[name]
+	formattersMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("[logger] unknown formatter: %s", config.Name)
+	}
+
+	if len(config.TimestampFormat) == 0 {
+		config.TimestampFormat = time.RFC3339
+	}
+
+	return factory(config)
+}
+
+func newTextFormatter(config FormatterConfig) (logrus.Formatter, error) {
+	if config.Colors {
+		return &textFormatter{
+			theme:            themeOrDefault(config.Theme),
+			timestampFormat:  config.TimestampFormat,
+			disableTimestamp: config.DisableTimestamp,
+			fieldMap:         textFieldMap(config.FieldMap),
+		}, nil
+	}
+
+	return &logrus.TextFormatter{
+		DisableColors:          true,
+		DisableTimestamp:       config.DisableTimestamp,
+		FullTimestamp:          true,
+		TimestampFormat:        config.TimestampFormat,
+		DisableSorting:         false,
+		DisableLevelTruncation: true,
+		QuoteEmptyFields:       true,
+		FieldMap:               fieldMap(config.FieldMap),
+	}, nil
+}
+
+func newLogfmtFormatter(config FormatterConfig) (logrus.Formatter, error) {
+	return &logrus.TextFormatter{
+		DisableColors:    true,
+		DisableTimestamp: config.DisableTimestamp,
+		FullTimestamp:    true,
+		TimestampFormat:  config.TimestampFormat,
+		QuoteEmptyFields: true,
+		FieldMap:         fieldMap(config.FieldMap),
+	}, nil
+}
+
+func newJSONFormatter(config FormatterConfig) (logrus.Formatter, error) {
+	return &logrus.JSONFormatter{
+		TimestampFormat:  config.TimestampFormat,
+		DisableTimestamp: config.DisableTimestamp,
+		DataKey:          config.DataKey,
+		FieldMap:         fieldMap(config.FieldMap),
+		PrettyPrint:      config.PrettyPrint,
+	}, nil
+}
+
+// fieldMap converts the configured renames into a logrus.FieldMap.
+func fieldMap(renames map[string]string) logrus.FieldMap {
+	if len(renames) == 0 {
+		return nil
+	}
+
+	fm := logrus.FieldMap{
+		logrus.FieldKeyTime:        logrus.FieldKeyTime,
+		logrus.FieldKeyLevel:       logrus.FieldKeyLevel,
+		logrus.FieldKeyMsg:         logrus.FieldKeyMsg,
+		logrus.FieldKeyLogrusError: logrus.FieldKeyLogrusError,
+		logrus.FieldKeyFunc:        logrus.FieldKeyFunc,
+		logrus.FieldKeyFile:        logrus.FieldKeyFile,
+	}
+	for key := range fm {
+		if name, ok := renames[string(key)]; ok && len(name) > 0 {
+			fm[key] = name
+		}
+	}
+	return fm
+}
+
+// textFieldMap returns the resolved renames of the default fields.
+func textFieldMap(renames map[string]string) map[string]string {
+	fm := fieldMap(renames)
+	if fm == nil {
+		return nil
+	}
+
+	keys := make(map[string]string, len(fm))
+	for key, name := range fm {
+		keys[string(key)] = name
+	}
+	return keys
+}
+
+// textFormatter is the colored text formatter,
+// it mimics the logrus.TextFormatter output using the Theme colors.
+// When a FieldMap is set the time, level and message are printed
+// as key=value pairs too, so that the renamed keys are preserved.
+type textFormatter struct {
+	theme            *Theme
+	timestampFormat  string
+	disableTimestamp bool
+	fieldMap         map[string]string
+}
+
+// key returns the configured name of a default field.
+func (f *textFormatter) key(key string) string {
+	if name, ok := f.fieldMap[key]; ok {
+		return name
+	}
+	return key
+}
+
+// Format renders a single log entry.
+func (f *textFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+
+	levelText := colored(strings.ToUpper(entry.Level.String()), f.theme.LevelColor(entry.Level))
+	message := strings.TrimSuffix(entry.Message, "\n")
+
+	switch {
+	case f.fieldMap != nil:
+		if !f.disableTimestamp {
+			f.appendKeyValue(b, f.key(logrus.FieldKeyTime), colored(formatValue(entry.Time.Format(f.timestampFormat)), f.theme.Muted))
+		}
+		f.appendKeyValue(b, f.key(logrus.FieldKeyLevel), levelText)
+		f.appendKeyValue(b, f.key(logrus.FieldKeyMsg), colored(formatValue(message), f.theme.Value))
+	case f.disableTimestamp:
+		fmt.Fprintf(b, "%s %-44s ", levelText, message)
+	default:
+		timestamp := colored("["+entry.Time.Format(f.timestampFormat)+"]", f.theme.Muted)
+		fmt.Fprintf(b, "%s%s %-44s ", levelText, timestamp, message)
+	}
+
+	data := make(logrus.Fields, len(entry.Data)+2)
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	if entry.HasCaller() {
+		data[f.key(logrus.FieldKeyFunc)] = entry.Caller.Function
+		data[f.key(logrus.FieldKeyFile)] = fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		f.appendKeyValue(b, k, colored(formatValue(data[k]), f.theme.Value))
+	}
+
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// appendKeyValue writes a space separated key=value pair, value is already colored.
+func (f *textFormatter) appendKeyValue(b *bytes.Buffer, key, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	fmt.Fprintf(b, "%s=%s", colored(key, f.theme.Key), value)
+}
+
+// formatValue returns the value as a string, quoted if needed.
+func formatValue(value interface{}) string {
+	var s string
+	if err, ok := value.(error); ok {
+		s = err.Error()
+	} else {
+		s = fmt.Sprint(value)
+	}
+
+	if needsQuoting(s) {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, ch := range s {
+		if !((ch >= 'a' && ch <= 'z') ||
+			(ch >= 'A' && ch <= 'Z') ||
+			(ch >= '0' && ch <= '9') ||
+			ch == '-' || ch == '.' || ch == '_' || ch == '/' || ch == '@' || ch == '^' || ch == '+' || ch == ':') {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package ansilog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func formatEntry(t *testing.T, formatter logrus.Formatter) string {
+	t.Helper()
+	entry := logrus.NewEntry(logrus.New())
+	entry.Time = time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC)
+	entry.Level = logrus.InfoLevel
+	entry.Message = "hello"
+	entry.Data = logrus.Fields{"key": "value"}
+
+	b, err := formatter.Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestNewFormatter(t *testing.T) {
+	tests := []struct {
+		config FormatterConfig
+		want   string
+	}{
+		{
+			FormatterConfig{},
+			"time=\"2020-05-01T10:30:00Z\" level=info msg=hello key=value\n",
+		},
+		{
+			FormatterConfig{Name: "JSON"},
+			`{"key":"value","level":"info","msg":"hello","time":"2020-05-01T10:30:00Z"}` + "\n",
+		},
+		{
+			FormatterConfig{Name: FormatterLogfmt, TimestampFormat: "2006-01-02"},
+			"time=2020-05-01 level=info msg=hello key=value\n",
+		},
+		{
+			FormatterConfig{Name: FormatterJSON, DisableTimestamp: true, DataKey: "data"},
+			`{"data":{"key":"value"},"level":"info","msg":"hello"}` + "\n",
+		},
+		{
+			FormatterConfig{Name: FormatterText, DisableTimestamp: true,
+				FieldMap: map[string]string{"msg": "message", "level": "severity", "unknown": "x"}},
+			"severity=info message=hello key=value\n",
+		},
+		{
+			FormatterConfig{Name: FormatterJSON, FieldMap: map[string]string{"time": "@timestamp", "msg": ""}},
+			`{"@timestamp":"2020-05-01T10:30:00Z","key":"value","level":"info","msg":"hello"}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		formatter, err := NewFormatter(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatEntry(t, formatter); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.config, got, test.want)
+		}
+	}
+
+	if _, err := NewFormatter(FormatterConfig{Name: "xml"}); err == nil || !strings.Contains(err.Error(), "unknown formatter: xml") {
+		t.Errorf("expected an unknown formatter error, got: %v", err)
+	}
+}
+
+func TestRegisterFormatter(t *testing.T) {
+	formattersMutex.Lock()
+	previous, registered := formatters["custom"]
+	formattersMutex.Unlock()
+	defer func() {
+		formattersMutex.Lock()
+		defer formattersMutex.Unlock()
+		if registered {
+			formatters["custom"] = previous
+		} else {
+			delete(formatters, "custom")
+		}
+	}()
+
+	var received FormatterConfig
+	RegisterFormatter("Custom", func(config FormatterConfig) (logrus.Formatter, error) {
+		received = config
+		prefix := fmt.Sprint(config.Options["prefix"])
+		return formatterFunc(func(entry *logrus.Entry) ([]byte, error) {
+			return []byte(prefix + entry.Message + "\n"), nil
+		}), nil
+	})
+
+	out := &bytes.Buffer{}
+	logger, err := NewWithConfig(Config{Out: out, Formatter: FormatterConfig{
+		Name:    "custom",
+		Options: map[string]interface{}{"prefix": "> "},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("hello")
+	if got := out.String(); got != "> hello\n" {
+		t.Errorf("unexpected output: %q", got)
+	}
+	if received.TimestampFormat != time.RFC3339 {
+		t.Errorf("the default timestamp format must be passed to the factory, got %q", received.TimestampFormat)
+	}
+}
+
+func TestNewWithConfigPath_Formatter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ansilog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logger.yml")
+	data := "level: debug\nformatter:\n  name: json\n  disable_timestamp: true\n  field_map:\n    msg: message\n"
+	if err = ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := NewWithConfigPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	logger.Logger.SetOutput(out)
+	logger.Debug("hello")
+
+	if got, want := out.String(), `{"level":"debug","message":"hello"}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+type formatterFunc func(entry *logrus.Entry) ([]byte, error)
+
+func (f formatterFunc) Format(entry *logrus.Entry) ([]byte, error) {
+	return f(entry)
+}
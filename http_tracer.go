@@ -2,23 +2,41 @@ package ansilog
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
 )
 
 const defaultLogTemplate = "{{.Host}} {{.Time}} | {{.Latency}} | {{.Status}} | {{.Method}} {{.RequestURI}}"
 
+// CommonLogTemplate is the NCSA Common Log Format, eg.:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 +0000] "GET /apache_pb.gif HTTP/1.0" 200 2326
+//
+// Use it with WithTemplateText or ParseTemplate.
+const CommonLogTemplate = `{{dash .Entry.RemoteIP}} - {{dash .Entry.User}} [{{clfTime .Entry.Time}}] "{{escape .Entry.Method}} {{escape .RequestURI}} {{escape .Entry.Proto}}" {{.Entry.SentStatus}} {{dash .Entry.Bytes}}`
+
+// CombinedLogTemplate is the NCSA Combined Log Format,
+// the Common Log Format plus referer and user agent.
+const CombinedLogTemplate = CommonLogTemplate + ` "{{escape (dash .Entry.Referer)}}" "{{escape (dash .Entry.UserAgent)}}"`
+
 type SkipperFunc func(r *http.Request) (shouldSkip bool)
 
 type HttpTracer struct {
+	// Logger is the stdlib logger used when no *ansilog.Logger
+	// backend is set, see WithLogger.
 	*log.Logger
 
 	TimeFormat string
@@ -35,73 +53,274 @@ type HttpTracer struct {
 	// 	UserAgent
 	// 	RequestURI
 	// 	Proto
+	// 	ProtoVersion
+	// 	RequestBytes
+	// 	Referer
+	// 	ClientIP (from X-Forwarded-For or X-Real-IP)
+	// 	RequestID
+	// 	TraceID
+	// 	SpanID
+	// 	Route (the matched route pattern, see WithRouteFunc)
+	// 	Query
+	// 	TLSVersion
+	// 	TLSCipher
+	// 	TTFB (time to first byte)
+	// 	Entry (the typed AccessLogEntry)
+	// Status is "hijacked" or "streamed" for the connections hijacked or flushed
+	// by the handler, Latency is then their duration,
+	// and "no response" if the handler wrote nothing.
 	// Default format is: "{{.Host}} {{.Time}} | {{.Latency}} | {{.Status}} | {{.Method}} {{.RequestURI}}"
 	Template *template.Template
 
 	Skipper SkipperFunc
 
-	// Colors ------------------------------------------------
+	// Header is printed once, before the first access log,
+	// eg.: the W3C Extended Log Format directives.
+	// It is printed in the text format only, without a Logger backend.
+	Header string
+
+	// Format is the access log format: text, json or logfmt.
+	// The text format renders the Template, json and logfmt
+	// print the typed AccessLogEntry, with time in RFC3339 format.
+	// It is ignored with a Logger backend, its formatter is used instead.
+	// Optional. Default value is text.
+	Format string
+
+	// ColorsMode determine if colors must be used or not.
+	// Default value is ConsoleColorsModeAuto, colors are used
+	// only if the tracer output is a terminal.
+	ColorsMode ConsoleColorsModeEnum
+
+	// Theme is the set of colors used to paint methods, status codes and host.
+	// Default value is DefaultTheme.
+	Theme *Theme
+
+	// RequestIDGenerator generates the request id when the request
+	// has no valid X-Request-ID header, the id is set on the response header
+	// and stored in the request context, see RequestIDFromContext.
+	// A nil generator disables the generation, incoming ids are still propagated.
+	// Default value is NewUUID.
+	RequestIDGenerator RequestIDGenerator
+
+	// ClientTemplate is the template of the outgoing requests logs, see Transport.
+	// Possible fields are: Time, Host, Status, Method, Latency, URL, Error
+	// and Entry (the typed ClientLogEntry).
+	ClientTemplate *template.Template
+
+	// MaxRetries is the number of retries of the outgoing idempotent requests,
+	// see Transport.
+	// Optional. Default value is 0, no retry.
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry, doubled at every attempt.
+	RetryBackoff time.Duration
+
+	// RedactedQueryParams are the query parameters redacted from the outgoing requests URL,
+	// matched case insensitively.
+	// Default value is DefaultRedactedQueryParams.
+	RedactedQueryParams []string
+
+	// Recovery enables the panic recovery in the middlewares, see WithRecovery.
+	Recovery bool
+
+	// RecoveryHandler writes the response of the requests whose handler panicked.
+	// Optional. Default response is a 500 Internal Server Error.
+	RecoveryHandler RecoveryHandlerFunc
+
+	// RouteFunc returns the route pattern matched by r,
+	// the echo middleware uses the echo route path instead.
+	// Optional.
+	RouteFunc func(r *http.Request) string
+
+	// logger is the optional *ansilog.Logger backend.
+	logger *Logger
+
+	colors colorsCache
+
+	headerOnce sync.Once
+}
+
+// HttpTracerOption configures an HttpTracer, see NewHttpTracerWithOptions.
+type HttpTracerOption func(hl *HttpTracer)
+
+// WithSkipper set the func used to skip requests.
+func WithSkipper(skipper SkipperFunc) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.Skipper = skipper
+	}
+}
+
+// WithWriter set the writer where the access logs are written.
+// Default value is os.Stdout.
+func WithWriter(w io.Writer) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.Logger = log.New(w, "", 0)
+	}
+}
+
+// WithLogger set an *ansilog.Logger as the access logs backend,
+// the access logs then go through its hooks (pghook included)
+// and share its formatter and level.
+// Requests are logged at info level, 4xx at warn level and 5xx at error level.
+func WithLogger(logger *Logger) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.logger = logger
+	}
+}
+
+// WithFormat set the access log format: text, json or logfmt.
+func WithFormat(format string) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.Format = format
+	}
+}
+
+// WithColorsMode set the tracer ColorsMode.
+func WithColorsMode(mode ConsoleColorsModeEnum) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.ColorsMode = mode
+	}
+}
+
+// WithTheme set the tracer Theme.
+func WithTheme(theme *Theme) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.Theme = theme
+	}
+}
+
+// WithTemplate set the tracer Template.
+func WithTemplate(tpl *template.Template) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.Template = tpl
+	}
+}
+
+// WithTemplateText parse text with ParseTemplate and set it as the tracer Template,
+// it panics if the template is invalid, eg.:
+//
+//	NewHttpTracerWithOptions(WithTemplateText(CombinedLogTemplate))
+func WithTemplateText(text string) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.Template = template.Must(ParseTemplate(text))
+	}
+}
+
+// WithW3CExtendedFormat set the W3C Extended Log Format Template and Header,
+// with the given fields, see W3CLogTemplate.
+func WithW3CExtendedFormat(fields ...string) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		text, header := W3CLogTemplate(fields...)
+		hl.Template = template.Must(ParseTemplate(text))
+		hl.Header = header
+	}
+}
+
+// WithRequestIDGenerator set the request id generator, eg.: NewUUID or NewULID.
+func WithRequestIDGenerator(generator RequestIDGenerator) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.RequestIDGenerator = generator
+	}
+}
+
+// WithRouteFunc set the func used to get the matched route pattern,
+// eg. with gorilla/mux:
+//
+//	WithRouteFunc(func(r *http.Request) string {
+//		route, _ := mux.CurrentRoute(r).GetPathTemplate()
+//		return route
+//	})
+func WithRouteFunc(routeFunc func(r *http.Request) string) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.RouteFunc = routeFunc
+	}
+}
 
-	black   Painter
-	white   Painter
-	red     Painter
-	green   Painter
-	yellow  Painter
-	blue    Painter
-	magenta Painter
-	cyan    Painter
+// WithTimeFormat set the tracer TimeFormat.
+func WithTimeFormat(layout string) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.TimeFormat = layout
+	}
 }
 
 // NewHttpTracer returns a new HttpTracer instance.
 func NewHttpTracer(skipper SkipperFunc) *HttpTracer {
-	out := os.Stdout
+	return NewHttpTracerWithOptions(WithSkipper(skipper))
+}
 
+// NewHttpTracerWithOptions returns a new HttpTracer instance
+// configured by the given options, eg.:
+//
+//	NewHttpTracerWithOptions(WithLogger(logger), WithSkipper(skipper))
+func NewHttpTracerWithOptions(options ...HttpTracerOption) *HttpTracer {
 	tracer := &HttpTracer{
-		Logger:     log.New(out, "", 0),
+		Logger:     log.New(os.Stdout, "", 0),
 		TimeFormat: "2006-01-02 15:04:05.000 MST", //time.RFC3339Nano time.RFC822Z, //"2006-01-02 15:04:05"
-		Template:   template.Must(template.New("ansilog_parser").Parse(defaultLogTemplate)),
-		Skipper:    skipper,
-	}
-
-	if IsTerm(out) {
-		tracer.black = NewPainter(black)
-		tracer.white = NewPainter(white)
-		tracer.red = NewPainter(red)
-		tracer.green = NewPainter(green)
-		tracer.yellow = NewPainter(yellow)
-		tracer.blue = NewPainter(blue)
-		tracer.magenta = NewPainter(magenta)
-		tracer.cyan = NewPainter(cyan)
-	} else {
-		painter := func(arg interface{}) string {
-			return fmt.Sprint(arg)
-		}
+		Template:   template.Must(ParseTemplate(defaultLogTemplate)),
+		Theme:      DefaultTheme,
+
+		RequestIDGenerator: NewUUID,
+
+		ClientTemplate:      template.Must(ParseTemplate(defaultClientLogTemplate)),
+		RetryBackoff:        100 * time.Millisecond,
+		RedactedQueryParams: DefaultRedactedQueryParams,
+	}
 
-		tracer.black = painter
-		tracer.white = painter
-		tracer.red = painter
-		tracer.green = painter
-		tracer.yellow = painter
-		tracer.blue = painter
-		tracer.magenta = painter
-		tracer.cyan = painter
+	for _, option := range options {
+		option(tracer)
 	}
 
 	return tracer
 }
 
-func (hl *HttpTracer) trace(rw interface{}, r *http.Request, start time.Time) {
+// colorsEnabled resolve the ColorsMode against the tracer output,
+// the result is cached until ColorsMode or the output change.
+// With a Logger backend colors are used in auto mode
+// only if the Logger uses colors.
+func (hl *HttpTracer) colorsEnabled() bool {
+	if hl.logger != nil && hl.ColorsMode == ConsoleColorsModeAuto {
+		return hl.logger.colors
+	}
+	return hl.colors.enabled(hl.ColorsMode, hl.Writer())
+}
+
+// paint return the argument as a color escaped string if colors are enabled.
+func (hl *HttpTracer) paint(color Color, arg interface{}) string {
+	return paint(color, arg, hl.colorsEnabled())
+}
+
+func (hl *HttpTracer) trace(rw *responseWriter, r *http.Request, rt *requestTrace) {
 
-	if hl.Skipper(r) {
+	if hl.Skipper != nil && hl.Skipper(r) {
 		return
 	}
 
+	start := rt.start
 	latency := time.Since(start)
 	if latency > time.Minute {
 		// truncate to seconds
 		latency = latency - latency%time.Second
 	}
 
+	if len(rt.route) == 0 && hl.RouteFunc != nil {
+		rt.route = hl.RouteFunc(r)
+	}
+
+	entry := newAccessLogEntry(rw, r, rt, latency)
+
+	if hl.logger == nil {
+		switch strings.ToLower(hl.Format) {
+		case FormatterJSON:
+			hl.Println(entry.JSON())
+			return
+		case FormatterLogfmt:
+			hl.Println(entry.Logfmt())
+			return
+		}
+	}
+
+	theme := themeOrDefault(hl.Theme)
+
 	metricsEntry := struct {
 		Time          string
 		Proto         string
@@ -113,150 +332,199 @@ func (hl *HttpTracer) trace(rw interface{}, r *http.Request, start time.Time) {
 		Host          string
 		RequestURI    string
 		UserAgent     string
+		ProtoVersion  string
+		RequestBytes  string
+		Referer       string
+		ClientIP      string
+		RequestID     string
+		TraceID       string
+		SpanID        string
+		Route         string
+		Query         string
+		TLSVersion    string
+		TLSCipher     string
+		TTFB          string
+		Entry         AccessLogEntry
 	}{
-		Time:       start.UTC().Format(hl.TimeFormat),
-		Proto:      r.Proto,
-		RemoteAddr: fmt.Sprintf("%-14s", r.RemoteAddr),
-		Status:     fmt.Sprintf("%3s", hl.fetchStatusCode(rw)),
-		Method:     hl.coloredMethod(r.Method),
-		Latency:    fmt.Sprintf("%13s", latency),
-		//ContentLength: fmt.Sprintf("%12s bytes", hl.fetchLength(rw)),
-		Host:       hl.blue("[") + hl.yellow(r.Host) + hl.blue("]"), // fmt.Sprintf("%-22s", r.Host),
-		RequestURI: r.RequestURI,                                    // path will exclude '/v1'
-		UserAgent:  r.UserAgent(),
+		Time:          start.UTC().Format(hl.TimeFormat),
+		Proto:         r.Proto,
+		RemoteAddr:    fmt.Sprintf("%-14s", r.RemoteAddr),
+		Status:        fmt.Sprintf("%3s", hl.coloredStatus(entry)),
+		Method:        hl.coloredMethod(r.Method),
+		Latency:       fmt.Sprintf("%13s", latency),
+		ContentLength: strconv.Itoa(entry.Bytes),
+		Host:          hl.paint(theme.Punctuation, "[") + hl.paint(theme.Host, r.Host) + hl.paint(theme.Punctuation, "]"), // fmt.Sprintf("%-22s", r.Host),
+		RequestURI:    r.RequestURI,                                                                                       // path will exclude '/v1'
+		UserAgent:     r.UserAgent(),
+		ProtoVersion:  fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor),
+		RequestBytes:  strconv.FormatInt(entry.RequestBytes, 10),
+		Referer:       entry.Referer,
+		ClientIP:      entry.ClientIP,
+		RequestID:     entry.RequestID,
+		TraceID:       entry.TraceID,
+		SpanID:        entry.SpanID,
+		Route:         entry.Route,
+		Query:         entry.Query,
+		TLSVersion:    entry.TLSVersion,
+		TLSCipher:     entry.TLSCipher,
+		TTFB:          rt.ttfb().String(),
+		Entry:         entry,
 	}
 	buff := &bytes.Buffer{}
 	if err := hl.Template.Execute(buff, metricsEntry); err != nil {
-		fmt.Println(hl.red(err))
-	} else {
+		hl.templateError(r.Context(), err)
+		return
+	}
+
+	if hl.logger == nil {
+		if len(hl.Header) > 0 {
+			hl.headerOnce.Do(func() { hl.Println(hl.Header) })
+		}
 		hl.Println(buff.String())
+		return
 	}
-}
 
-// MethodColor is the ANSI color for appropriately logging http method to a terminal.
-func (hl *HttpTracer) coloredMethod(method string) string {
-	resizedMethod := fmt.Sprintf("%-7s", method)
-	switch method {
-	case "GET":
-		return hl.green(resizedMethod)
-		//return Blue(method)
-	case "POST":
-		return hl.blue(resizedMethod)
-		//return Cyan(method)
-	case "PUT":
-		return hl.cyan(resizedMethod)
-		//return Yellow(method)
-	case "DELETE":
-		return hl.red(resizedMethod)
-	case "PATCH":
-		return hl.yellow(resizedMethod)
-		//return hl.green(method)
-	case "HEAD":
-		return hl.magenta(resizedMethod)
-	case "OPTIONS":
-		return hl.white(resizedMethod)
-	default:
-		return resizedMethod
+	level := statusLevel(entry.Status)
+	if entry.State == StateNoResponse {
+		level = logrus.WarnLevel
 	}
+	hl.logger.WithContext(r.Context()).WithFields(entry.Fields()).Log(level, buff.String())
 }
 
-// fetchStatusCode attempts to see if the passed type implements a Status() method.
-// If so, it is called and the value is returned.
-func (hl *HttpTracer) fetchStatusCode(rw interface{}) string {
-	statusCode := 0
-
-	// Compatible with negroni custom ResponseWriter
-	if crw, ok := rw.(interface{ Status() int }); ok {
-		statusCode = crw.Status()
-	} else if echoResponse, ok := rw.(*echo.Response); ok {
-		statusCode = echoResponse.Status
+// templateError reports a template execution error
+// at error level on the Logger backend or on the tracer output.
+func (hl *HttpTracer) templateError(ctx context.Context, err error) {
+	if hl.logger != nil {
+		hl.logger.WithContext(ctx).WithError(err).Error("[HttpTracer] template execution failed")
+		return
 	}
+	hl.Println(hl.paint(themeOrDefault(hl.Theme).Status5xx, err))
+}
 
+// statusLevel returns the log level of the given status code.
+func statusLevel(statusCode int) logrus.Level {
 	switch {
-	case statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices: // 200 300
-		return hl.green(strconv.Itoa(statusCode))
-	case statusCode >= http.StatusMultipleChoices && statusCode < http.StatusBadRequest: // redirects... 300 400
-		return hl.cyan(strconv.Itoa(statusCode))
-	case statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError: // client errors... 400 500
-		return hl.magenta(strconv.Itoa(statusCode))
-	case statusCode >= http.StatusInternalServerError: // server error
-		return hl.red(strconv.Itoa(statusCode))
+	case statusCode >= http.StatusInternalServerError:
+		return logrus.ErrorLevel
+	case statusCode >= http.StatusBadRequest:
+		return logrus.WarnLevel
 	default:
-		return hl.red("unknown status")
+		return logrus.InfoLevel
 	}
 }
 
-func (hl *HttpTracer) fetchLength(rw interface{}) string {
-	var length int
-	if crw, ok := rw.(responseWriter); ok {
-		length = crw.Size()
-	} else if echoResponse, ok := rw.(*echo.Response); ok {
-		length = int(echoResponse.Size)
-	}
-	return strconv.Itoa(length)
+// coloredMethod paint the http method with the Theme color.
+func (hl *HttpTracer) coloredMethod(method string) string {
+	return hl.paint(themeOrDefault(hl.Theme).MethodColor(method), fmt.Sprintf("%-7s", method))
 }
 
-// Middleware ----------------------------------------------------------------------------------------------------------
-
-// responseWriter is a custom http.ResponseWriter that holds statusCode and contentLength
-type responseWriter struct {
-	http.ResponseWriter
-	status int
-	size   int
+// coloredStatus paint the status code followed, for hijacked and streamed
+// connections, by the connection state, the state alone is painted
+// if no status has been written.
+func (hl *HttpTracer) coloredStatus(entry AccessLogEntry) string {
+	if len(entry.State) == 0 {
+		return hl.coloredStatusCode(entry.Status)
+	}
+	state := hl.paint(themeOrDefault(hl.Theme).Muted, entry.State)
+	if entry.Status == 0 {
+		return state
+	}
+	return hl.coloredStatusCode(entry.Status) + " " + state
 }
 
-func (rw *responseWriter) WriteHeader(status int) {
-	rw.status = status
-	rw.ResponseWriter.WriteHeader(status)
+// coloredStatusCode paint the status code with the Theme color.
+func (hl *HttpTracer) coloredStatusCode(statusCode int) string {
+	color := themeOrDefault(hl.Theme).StatusColor(statusCode)
+	if statusCode < http.StatusOK {
+		return hl.paint(color, "unknown status")
+	}
+	return hl.paint(color, strconv.Itoa(statusCode))
 }
 
-func (rw *responseWriter) Header() http.Header {
-	return rw.ResponseWriter.Header()
-}
+// Middleware ----------------------------------------------------------------------------------------------------------
 
-func (rw *responseWriter) Write(bytes []byte) (int, error) {
-	if rw.status == 0 {
-		rw.status = http.StatusOK
+// begin starts tracing the request, resolves its request id
+// and its span context (a child of the incoming one or a new trace),
+// it returns the request with both in its context.
+func (hl *HttpTracer) begin(w http.ResponseWriter, r *http.Request) (*requestTrace, *http.Request) {
+	rt := newRequestTrace(r)
+	ctx := r.Context()
+
+	id := r.Header.Get(echo.HeaderXRequestID)
+	if !validRequestID(id) {
+		id = ""
+		if hl.RequestIDGenerator != nil {
+			id = hl.RequestIDGenerator()
+		}
+	}
+	if len(id) > 0 {
+		w.Header().Set(echo.HeaderXRequestID, id)
+		ctx = ContextWithRequestID(ctx, id)
 	}
-	size, err := rw.ResponseWriter.Write(bytes)
-	rw.size += size
-	return size, err
-}
 
-func (rw *responseWriter) Status() int {
-	return rw.status
-}
+	sc := NewSpanContext()
+	if parent, ok := ExtractSpanContext(r.Header); ok {
+		sc = parent.Child()
+	}
+	ctx = ContextWithSpanContext(ctx, sc)
 
-func (rw *responseWriter) Size() int {
-	return rw.size
+	return rt, r.WithContext(ctx)
 }
 
 // HTTPLogHandlerFunc is an http.HandlerFunc middleware.
 func (hl *HttpTracer) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		nrw := &responseWriter{rw, http.StatusOK, 0}
-		defer hl.trace(nrw, r, start)
-		next.ServeHTTP(nrw, r)
+		rt, r := hl.begin(rw, r)
+		nrw, w := newResponseWriter(rw, rt)
+		defer hl.trace(nrw, r, rt)
+		if hl.Recovery {
+			defer func() {
+				if rec := recover(); rec != nil {
+					hl.recovered(w, r, nrw, rec, func() { internalServerError(w) })
+				}
+			}()
+		}
+		next.ServeHTTP(w, r)
 	}
 }
 
 // HTTPLogHandler is an http.Handler middleware.
 func (hl *HttpTracer) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		nrw := &responseWriter{rw, http.StatusOK, 0}
-		defer hl.trace(nrw, r, start)
-		next.ServeHTTP(nrw, r)
+		rt, r := hl.begin(rw, r)
+		nrw, w := newResponseWriter(rw, rt)
+		defer hl.trace(nrw, r, rt)
+		if hl.Recovery {
+			defer func() {
+				if rec := recover(); rec != nil {
+					hl.recovered(w, r, nrw, rec, func() { internalServerError(w) })
+				}
+			}()
+		}
+		next.ServeHTTP(w, r)
 	})
 }
 
 // EchoHTTPHandler is an Echo middleware.
 func (hl *HttpTracer) EchoMiddlewareFunc(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		r := c.Request()
-		start := time.Now()
-		defer hl.trace(c.Response(), r, start)
+		rt, r := hl.begin(c.Response(), c.Request())
+		c.SetRequest(r)
+		var nrw *responseWriter
+		nrw, c.Response().Writer = newResponseWriter(c.Response().Writer, rt)
+		defer func() {
+			rt.route = c.Path()
+			hl.trace(nrw, r, rt)
+		}()
+		if hl.Recovery {
+			defer func() {
+				if rec := recover(); rec != nil {
+					hl.recovered(c.Response(), r, nrw, rec, func() {
+						c.Error(echo.NewHTTPError(http.StatusInternalServerError))
+					})
+				}
+			}()
+		}
 		if err := next(c); err != nil {
 			c.Error(err)
 		}
@@ -264,10 +532,25 @@ func (hl *HttpTracer) EchoMiddlewareFunc(next echo.HandlerFunc) echo.HandlerFunc
 	}
 }
 
-// Negroni interface
+// Negroni interface, negroni.Negroni already passes a negroni.ResponseWriter,
+// it is wrapped as is so that the next handlers see its exact optional interfaces
+// and can still assert it to negroni.ResponseWriter.
 func (hl *HttpTracer) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	start := time.Now()
-	nrw := negroni.NewResponseWriter(rw)
-	defer hl.trace(nrw, r, start)
-	next(nrw, r)
+	rt, r := hl.begin(rw, r)
+	var nrw *responseWriter
+	var w http.ResponseWriter
+	if nw, ok := rw.(negroni.ResponseWriter); ok {
+		nrw, w = newNegroniResponseWriter(nw, rt)
+	} else {
+		nrw, w = newResponseWriter(rw, rt)
+	}
+	defer hl.trace(nrw, r, rt)
+	if hl.Recovery {
+		defer func() {
+			if rec := recover(); rec != nil {
+				hl.recovered(w, r, nrw, rec, func() { internalServerError(w) })
+			}
+		}()
+	}
+	next(w, r)
 }
@@ -0,0 +1,374 @@
+package ansilog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHttpTracer_WithWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithColorsMode(ConsoleColorsModeDisabled))
+
+	handler := tracer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	got := out.String()
+	if !strings.Contains(got, "| 404 | GET     /missing") {
+		t.Errorf("unexpected access log: %q", got)
+	}
+	if strings.Contains(got, esc) {
+		t.Errorf("unexpected colors in access log: %q", got)
+	}
+}
+
+func TestHttpTracer_WithLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, err := NewWithConfig(Config{Out: out, Formatter: FormatterConfig{Name: FormatterJSON}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := NewHttpTracerWithOptions(WithLogger(logger), WithSkipper(func(r *http.Request) bool {
+		return r.URL.Path == "/health"
+	}))
+
+	handler := tracer.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fail", nil))
+
+	got := out.String()
+	if strings.Count(got, "\n") != 1 {
+		t.Fatalf("expected a single entry, got: %q", got)
+	}
+	for _, want := range []string{`"level":"error"`, `"status":500`, `"method":"POST"`, `"path":"/fail"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in %q", want, got)
+		}
+	}
+	if strings.Contains(got, esc) {
+		t.Errorf("unexpected colors in json entry: %q", got)
+	}
+}
+
+func TestHttpTracer_NilSkipper(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracer(nil)
+	tracer.SetOutput(out)
+
+	tracer.Handler(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if out.Len() == 0 {
+		t.Error("expected an access log")
+	}
+}
+
+func TestHttpTracer_Format(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/users?page=2", nil)
+		r.Header.Set("User-Agent", "test agent")
+		return r
+	}
+
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithFormat(FormatterJSON))
+	tracer.HandlerFunc(handler).ServeHTTP(httptest.NewRecorder(), newRequest())
+
+	var entry AccessLogEntry
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if entry.Status != http.StatusCreated || entry.Bytes != 5 || entry.Method != http.MethodPost ||
+		entry.Path != "/users" || entry.Query != "page=2" || entry.RemoteIP != "192.0.2.1" ||
+		entry.UserAgent != "test agent" || entry.Proto != "HTTP/1.1" || entry.LatencyMs <= 0 {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if strings.Contains(out.String(), "referer") {
+		t.Errorf("empty referer must be omitted: %q", out.String())
+	}
+
+	out.Reset()
+	tracer.Format = FormatterLogfmt
+	tracer.HandlerFunc(handler).ServeHTTP(httptest.NewRecorder(), newRequest())
+
+	want := `status=201 latency_ms=`
+	if got := out.String(); !strings.HasPrefix(got, "time=") || !strings.Contains(got, want) ||
+		!strings.Contains(got, ` bytes=5 method=POST path=/users query="page=2" remote_ip=192.0.2.1 user_agent="test agent" proto=HTTP/1.1 `) {
+		t.Errorf("unexpected logfmt line: %q", got)
+	}
+}
+
+func TestHttpTracer_LogFormats(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/index.html?q=a%20b", nil)
+		r.SetBasicAuth("frank", "secret")
+		r.Header.Set("Referer", "http://example.com/")
+		r.Header.Set("User-Agent", `Mozilla/5.0 "test"`)
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		option  HttpTracerOption
+		pattern string
+	}{
+		{
+			"common",
+			WithTemplateText(CommonLogTemplate),
+			`^192\.0\.2\.1 - frank \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} \+0000\] "GET /index\.html\?q=a%20b HTTP/1\.1" 200 5\n$`,
+		},
+		{
+			"combined",
+			WithTemplateText(CombinedLogTemplate),
+			`^192\.0\.2\.1 - frank \[[^\]]+\] "GET /index\.html\?q=a%20b HTTP/1\.1" 200 5 "http://example\.com/" "Mozilla/5\.0 \\"test\\""\n$`,
+		},
+		{
+			"w3c",
+			WithW3CExtendedFormat("date", "c-ip", "cs-username", "cs-uri-stem", "cs-uri-query", "sc-status", "sc-bytes", "cs(User-Agent)", "x-unknown"),
+			`^#Version: 1\.0\n#Fields: date c-ip cs-username cs-uri-stem cs-uri-query sc-status sc-bytes cs\(User-Agent\) x-unknown\n` +
+				`\d{4}-\d{2}-\d{2} 192\.0\.2\.1 frank /index\.html q=a%20b 200 5 Mozilla/5\.0\+"test" -\n` +
+				`\d{4}-\d{2}-\d{2} 192\.0\.2\.1 frank /index\.html q=a%20b 200 5 Mozilla/5\.0\+"test" -\n$`,
+		},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		tracer := NewHttpTracerWithOptions(WithWriter(out), test.option)
+		tracer.HandlerFunc(handler).ServeHTTP(httptest.NewRecorder(), newRequest())
+		if test.name == "w3c" {
+			tracer.HandlerFunc(handler).ServeHTTP(httptest.NewRecorder(), newRequest())
+		}
+
+		if !regexp.MustCompile(test.pattern).MatchString(out.String()) {
+			t.Errorf("%s: unexpected output %q", test.name, out.String())
+		}
+	}
+
+	// net/http sends 200 OK when the handler writes nothing
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithTemplateText(CommonLogTemplate))
+	tracer.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(httptest.NewRecorder(), newRequest())
+	if !strings.HasSuffix(out.String(), `HTTP/1.1" 200 -`+"\n") {
+		t.Errorf("empty response: unexpected output %q", out.String())
+	}
+}
+
+func TestHttpTracer_TemplateFields(t *testing.T) {
+	const text = "{{.ContentLength}}|{{.RequestBytes}}|{{.Referer}}|{{.ClientIP}}|{{.RequestID}}|{{.Route}}|{{.Query}}|{{.ProtoVersion}}|{{.TLSVersion}}|{{.TLSCipher}}|{{.TTFB}}"
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/users/42?verbose=1", strings.NewReader("body"))
+		r.Header.Set("Referer", "http://example.com/")
+		r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+		r.Header.Set(echo.HeaderXRequestID, "abc")
+		r.TLS = &tls.ConnectionState{Version: tls.VersionTLS13, CipherSuite: tls.TLS_AES_128_GCM_SHA256}
+		return r
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte("hello"))
+	}
+
+	want := regexp.MustCompile(`^5\|4\|http://example\.com/\|203\.0\.113\.7\|abc\|/users/:id\|verbose=1\|1\.1\|TLS 1\.3\|TLS_AES_128_GCM_SHA256\|[0-9.]+[nµm]?s\n$`)
+
+	routeFunc := WithRouteFunc(func(r *http.Request) string { return "/users/:id" })
+
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithTemplateText(text), routeFunc)
+	tracer.HandlerFunc(handler).ServeHTTP(httptest.NewRecorder(), newRequest())
+	if !want.MatchString(out.String()) {
+		t.Errorf("net/http: unexpected output %q", out.String())
+	}
+
+	out.Reset()
+	tracer.ServeHTTP(httptest.NewRecorder(), newRequest(), handler)
+	if !want.MatchString(out.String()) {
+		t.Errorf("negroni: unexpected output %q", out.String())
+	}
+
+	out.Reset()
+	tracer = NewHttpTracerWithOptions(WithWriter(out), WithTemplateText(text))
+	e := echo.New()
+	e.Use(tracer.EchoMiddlewareFunc)
+	e.POST("/users/:id", echo.WrapHandler(http.HandlerFunc(handler)))
+	e.ServeHTTP(httptest.NewRecorder(), newRequest())
+	if !want.MatchString(out.String()) {
+		t.Errorf("echo: unexpected output %q", out.String())
+	}
+}
+
+func TestHttpTracer_RequestID(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, err := NewWithConfig(Config{Out: out, Formatter: FormatterConfig{Name: FormatterJSON}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := NewHttpTracerWithOptions(WithLogger(logger), WithRequestIDGenerator(NewULID))
+	handler := tracer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).Info("handling")
+	}))
+
+	// incoming id
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(echo.HeaderXRequestID, "incoming-id")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got := w.Header().Get(echo.HeaderXRequestID); got != "incoming-id" {
+		t.Errorf("response header: got %q", got)
+	}
+	if got := strings.Count(out.String(), `"request_id":"incoming-id"`); got != 2 {
+		t.Errorf("expected the request id in both entries, got: %q", out.String())
+	}
+
+	// generated id, invalid incoming ids are replaced
+	out.Reset()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(echo.HeaderXRequestID, "bad\nid")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	id := w.Header().Get(echo.HeaderXRequestID)
+	if !regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`).MatchString(id) {
+		t.Fatalf("invalid ULID: %q", id)
+	}
+	if got := strings.Count(out.String(), `"request_id":"`+id+`"`); got != 2 {
+		t.Errorf("expected the request id in both entries, got: %q", out.String())
+	}
+
+	// disabled generation
+	tracer.RequestIDGenerator = nil
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := w.Header().Get(echo.HeaderXRequestID); len(got) > 0 {
+		t.Errorf("unexpected request id: %q", got)
+	}
+}
+
+func TestRequestIDGenerators(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id := NewUUID(); !uuid.MatchString(id) {
+		t.Errorf("invalid UUID: %q", id)
+	}
+	if NewUUID() == NewUUID() {
+		t.Error("duplicated UUID")
+	}
+
+	first := NewULID()
+	time.Sleep(2 * time.Millisecond)
+	if second := NewULID(); second <= first {
+		t.Errorf("ULIDs must be sortable: %q >= %q", first, second)
+	}
+}
+
+func TestHttpTracer_StatusRecording(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+		noEcho  bool
+	}{
+		{
+			"explicit status",
+			func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) },
+			"202", false,
+		},
+		{
+			"implicit status",
+			func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) },
+			"200", false,
+		},
+		{
+			"first WriteHeader wins",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			"404", false,
+		},
+		{
+			// echo commits the response on the first WriteHeader, 1xx included
+			"informational headers",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusNoContent)
+			},
+			"204", true,
+		},
+		{
+			"no response",
+			func(w http.ResponseWriter, r *http.Request) {},
+			"no response", false,
+		},
+	}
+
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithColorsMode(ConsoleColorsModeDisabled), WithTemplateText("{{.Status}}"))
+
+	e := echo.New()
+	e.Use(tracer.EchoMiddlewareFunc)
+
+	for _, test := range tests {
+		e.GET("/"+strings.Replace(test.name, " ", "-", -1), echo.WrapHandler(test.handler))
+	}
+
+	adapters := map[string]func(test http.HandlerFunc) http.Handler{
+		"Handler":     func(h http.HandlerFunc) http.Handler { return tracer.Handler(h) },
+		"HandlerFunc": func(h http.HandlerFunc) http.Handler { return tracer.HandlerFunc(h) },
+		"negroni": func(h http.HandlerFunc) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { tracer.ServeHTTP(w, r, h) })
+		},
+		"echo": func(http.HandlerFunc) http.Handler { return e },
+	}
+
+	for name, adapter := range adapters {
+		for _, test := range tests {
+			if name == "echo" && test.noEcho {
+				continue
+			}
+
+			out.Reset()
+			r := httptest.NewRequest(http.MethodGet, "/"+strings.Replace(test.name, " ", "-", -1), nil)
+			adapter(test.handler).ServeHTTP(httptest.NewRecorder(), r)
+			if got := strings.TrimSpace(out.String()); got != test.want {
+				t.Errorf("%s, %s: got %q, want %q", name, test.name, got, test.want)
+			}
+		}
+	}
+}
+
+func TestHttpTracer_TemplateError(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithColorsMode(ConsoleColorsModeDisabled),
+		WithTemplateText("{{.Entry.Missing}}"))
+	tracer.Handler(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := out.String(); !strings.Contains(got, "Missing") {
+		t.Errorf("the template error must be written to the tracer output, got: %q", got)
+	}
+}
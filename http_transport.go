@@ -0,0 +1,383 @@
+package ansilog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultClientLogTemplate = "{{.Host}} {{.Time}} | {{.Latency}} | {{.Status}} | {{.Method}} {{.URL}}" +
+	"{{if gt .Entry.Attempts 1}} | attempts: {{.Entry.Attempts}}{{end}}{{if .Error}} | {{.Error}}{{end}}"
+
+// defaultClientTemplate is used when the ClientTemplate is nil,
+// eg.: for an HttpTracer not created by NewHttpTracerWithOptions.
+var defaultClientTemplate = template.Must(ParseTemplate(defaultClientLogTemplate))
+
+// DefaultRedactedQueryParams are the query parameters redacted from the outgoing requests URL.
+var DefaultRedactedQueryParams = []string{
+	"access_token", "api_key", "apikey", "auth", "authorization", "client_secret", "code", "id_token",
+	"key", "password", "passwd", "pwd", "refresh_token", "secret", "sig", "signature", "token",
+	"x-amz-credential", "x-amz-security-token", "x-amz-signature",
+}
+
+// redacted replaces credentials and sensitive values.
+const redacted = "REDACTED"
+
+// ClientLogEntry is the typed log of an outgoing request, see HttpTracer.Transport.
+type ClientLogEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Host      string    `json:"host"`
+	Status    int       `json:"status"`
+	LatencyMs float64   `json:"latency_ms"`
+	Bytes     int64     `json:"bytes"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	SpanID    string    `json:"span_id,omitempty"`
+}
+
+func (e ClientLogEntry) pairs() []kvPair {
+	pairs := []kvPair{
+		{"time", e.Time.Format(time.RFC3339Nano)},
+		{"method", e.Method},
+		{"url", e.URL},
+		{"host", e.Host},
+		{"status", e.Status},
+		{"latency_ms", e.LatencyMs},
+		{"bytes", e.Bytes},
+		{"attempts", e.Attempts},
+	}
+	for _, pair := range []kvPair{
+		{"error", e.Error},
+		{"request_id", e.RequestID},
+		{"trace_id", e.TraceID},
+		{"span_id", e.SpanID},
+	} {
+		if len(pair.value.(string)) > 0 {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// Fields returns the entry as logrus fields.
+func (e ClientLogEntry) Fields() logrus.Fields {
+	return logEntry(e.pairs()).fields()
+}
+
+// JSON returns the entry as a json object.
+func (e ClientLogEntry) JSON() string {
+	return logEntry(e.pairs()).json()
+}
+
+// Logfmt returns the entry as space separated key=value pairs.
+func (e ClientLogEntry) Logfmt() string {
+	return logEntry(e.pairs()).logfmt()
+}
+
+// Transport returns an http.RoundTripper that logs the outgoing requests
+// in the tracer format, through next (http.DefaultTransport if nil), eg.:
+//
+//	client := &http.Client{Transport: tracer.Transport(nil)}
+//
+// URL credentials (userinfo) and the RedactedQueryParams are redacted.
+// The request id and the span context of the request context are propagated
+// (X-Request-ID, traceparent, tracestate and b3 headers), the outgoing request
+// is logged as a child span. Headers already set on the request are kept.
+// Requests are logged when the response body is read to EOF or closed,
+// so that the body size is known, the latency is the time to the response headers.
+// A response body neither read to EOF nor closed is never logged.
+// Upgraded connections (101 Switching Protocols) are logged as soon as
+// the response is received, their body is returned as is.
+// Idempotent requests are retried MaxRetries times on network errors
+// and on 502, 503 and 504 responses.
+func (hl *HttpTracer) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &tracingTransport{tracer: hl, next: next}
+}
+
+// WithRetries set the number of retries of the outgoing idempotent requests
+// and the backoff before the first retry, doubled at every attempt.
+func WithRetries(maxRetries int, backoff time.Duration) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.MaxRetries = maxRetries
+		hl.RetryBackoff = backoff
+	}
+}
+
+// WithRedactedQueryParams set the query parameters redacted from the outgoing requests URL.
+func WithRedactedQueryParams(params ...string) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.RedactedQueryParams = params
+	}
+}
+
+type tracingTransport struct {
+	tracer *HttpTracer
+	next   http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hl := t.tracer
+	if hl.Skipper != nil && hl.Skipper(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	start := time.Now()
+	ctx := req.Context()
+
+	// a RoundTripper must not modify the request
+	req = req.Clone(ctx)
+
+	entry := ClientLogEntry{
+		Time:   start.UTC(),
+		Method: req.Method,
+		URL:    redactURL(req.URL, hl.RedactedQueryParams),
+		Host:   req.URL.Host,
+	}
+
+	if id := RequestIDFromContext(ctx); len(id) > 0 {
+		entry.RequestID = id
+		if len(req.Header.Get(echo.HeaderXRequestID)) == 0 {
+			req.Header.Set(echo.HeaderXRequestID, id)
+		}
+	}
+
+	// trace headers already set by the caller are kept
+	sc, traced := ExtractSpanContext(req.Header)
+	if !traced {
+		sc = NewSpanContext()
+		if parent, ok := SpanContextFromContext(ctx); ok {
+			sc = parent.Child()
+		}
+		req.Header.Set(HeaderTraceParent, sc.TraceParent())
+		if len(sc.TraceState) > 0 {
+			req.Header.Set(HeaderTraceState, sc.TraceState)
+		}
+		req.Header.Set(HeaderB3, sc.B3())
+	}
+	entry.TraceID, entry.SpanID = sc.TraceID, sc.SpanID
+
+	resp, attempts, err := t.roundTrip(req)
+	latency := time.Since(start)
+	entry.Attempts = attempts
+
+	if err != nil {
+		entry.Error = err.Error()
+		hl.traceClient(ctx, entry, latency)
+		return resp, err
+	}
+
+	entry.Status = resp.StatusCode
+
+	// the body of the 101 Switching Protocols responses is the upgraded
+	// connection, it must stay an io.ReadWriteCloser, eg.: for httputil.ReverseProxy.
+	if _, writable := resp.Body.(io.Writer); writable || resp.StatusCode == http.StatusSwitchingProtocols {
+		hl.traceClient(ctx, entry, latency)
+		return resp, nil
+	}
+
+	resp.Body = &tracingBody{ReadCloser: resp.Body, onClose: func(size int64) {
+		entry.Bytes = size
+		hl.traceClient(ctx, entry, latency)
+	}}
+	return resp, nil
+}
+
+// roundTrip sends the request, retrying it if possible.
+func (t *tracingTransport) roundTrip(req *http.Request) (resp *http.Response, attempts int, err error) {
+	backoff := t.tracer.RetryBackoff
+	for {
+		attempts++
+		resp, err = t.next.RoundTrip(req)
+		if attempts > t.tracer.MaxRetries || !retryable(req, resp, err) {
+			return resp, attempts, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, attempts, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, attempts, bodyErr
+			}
+			req.Body = body
+		}
+	}
+}
+
+// retryable reports whether the request is idempotent, can be sent again
+// and failed with a network error or a temporary server error.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// tracingBody counts the bytes read from a response body
+// and calls onClose once, when the body reaches EOF or is closed.
+type tracingBody struct {
+	io.ReadCloser
+	size    int64
+	once    sync.Once
+	onClose func(size int64)
+}
+
+func (b *tracingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *tracingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+func (b *tracingBody) done() {
+	b.once.Do(func() { b.onClose(b.size) })
+}
+
+// traceClient logs an outgoing request, latency is the time to the response headers.
+func (hl *HttpTracer) traceClient(ctx context.Context, entry ClientLogEntry, latency time.Duration) {
+	entry.LatencyMs = milliseconds(latency)
+
+	level := statusLevel(entry.Status)
+	if len(entry.Error) > 0 {
+		level = logrus.ErrorLevel
+	}
+
+	if hl.logger == nil {
+		switch strings.ToLower(hl.Format) {
+		case FormatterJSON:
+			hl.Println(entry.JSON())
+			return
+		case FormatterLogfmt:
+			hl.Println(entry.Logfmt())
+			return
+		}
+	}
+
+	theme := themeOrDefault(hl.Theme)
+
+	status := "error"
+	if entry.Status > 0 {
+		status = strconv.Itoa(entry.Status)
+	}
+
+	data := struct {
+		Time    string
+		Host    string
+		Status  string
+		Method  string
+		Latency string
+		URL     string
+		Error   string
+		Entry   ClientLogEntry
+	}{
+		Time:    entry.Time.Format(hl.TimeFormat),
+		Host:    hl.paint(theme.Punctuation, "[") + hl.paint(theme.Host, entry.Host) + hl.paint(theme.Punctuation, "]"),
+		Status:  fmt.Sprintf("%3s", hl.paint(theme.StatusColor(entry.Status), status)),
+		Method:  hl.coloredMethod(entry.Method),
+		Latency: fmt.Sprintf("%13s", latency),
+		URL:     entry.URL,
+		Error:   hl.paint(theme.Status5xx, entry.Error),
+		Entry:   entry,
+	}
+
+	tpl := hl.ClientTemplate
+	if tpl == nil {
+		tpl = defaultClientTemplate
+	}
+
+	buff := &bytes.Buffer{}
+	if err := tpl.Execute(buff, data); err != nil {
+		hl.templateError(ctx, err)
+		return
+	}
+
+	if hl.logger == nil {
+		hl.Println(buff.String())
+		return
+	}
+
+	hl.logger.WithContext(ctx).WithFields(entry.Fields()).Log(level, buff.String())
+}
+
+// redactURL returns the URL with the userinfo and the sensitive query
+// parameters values replaced, the parameters order is preserved.
+// The whole userinfo is redacted since a bare username can be a token too,
+// eg.: https://<token>@github.com.
+func redactURL(u *url.URL, params []string) string {
+	redactedURL := *u
+	if u.User != nil {
+		redactedURL.User = url.User(redacted)
+	}
+
+	if len(u.RawQuery) > 0 {
+		parts := strings.Split(u.RawQuery, "&")
+		for i, part := range parts {
+			kv := strings.SplitN(part, "=", 2)
+			key, err := url.QueryUnescape(kv[0])
+			if err != nil {
+				key = kv[0]
+			}
+			for _, param := range params {
+				if strings.EqualFold(key, param) {
+					parts[i] = kv[0] + "=" + redacted
+					break
+				}
+			}
+		}
+		redactedURL.RawQuery = strings.Join(parts, "&")
+	}
+
+	return redactedURL.String()
+}
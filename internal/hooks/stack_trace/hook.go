@@ -6,6 +6,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// StackKey is the entry field holding the stack-trace.
+const StackKey = "stack"
+
 // New returns a new stack_trace instance.
 // It automatically extracts stack-trace from errors created with "github.com/pkg/errors"
 func New() LogrusStackHook {
@@ -31,7 +34,7 @@ func (hook LogrusStackHook) Fire(entry *logrus.Entry) error {
 		// escape new lines
 		//consecutiveNewLines := regexp.MustCompile(`\n`)
 		//stack = consecutiveNewLines.ReplaceAllString(stack, "\n")
-		entry.Data["stack"] = stack
+		entry.Data[StackKey] = stack
 	}
 	return nil
 }
@@ -0,0 +1,379 @@
+package ansilog
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultDumpMaxDepth define the KVLogger.DumpMaxDepth default value.
+var defaultDumpMaxDepth = 8
+
+const (
+	dumpIndent = "  "
+	dumpMask   = "******"
+)
+
+// Dump prints v as an indented key-value tree.
+// Structs (honoring yaml and json tags), maps and slices are walked recursively,
+// map keys are sorted, struct fields keep their declaration order.
+// Fields tagged with `ansilog:"secret"` are masked
+// and fields tagged with `ansilog:"-"` are skipped.
+func (kvl *KVLogger) Dump(v interface{}) {
+	kvl.Fdump(kvl.out(), v)
+}
+
+// Fdump is like Dump but writes to w,
+// colors are used if enabled for w.
+func (kvl *KVLogger) Fdump(w io.Writer, v interface{}) {
+	kvl.write(w, kvl.sdump(v, kvl.colorsEnabledFor(w)))
+}
+
+// Sdump is like Dump but returns the resulting string.
+func (kvl *KVLogger) Sdump(v interface{}) string {
+	return kvl.sdump(v, kvl.colorsEnabled())
+}
+
+func (kvl *KVLogger) sdump(v interface{}, colors bool) string {
+	d := &dumper{
+		kvl:      kvl,
+		colors:   colors,
+		b:        &strings.Builder{},
+		maxDepth: kvl.DumpMaxDepth,
+		visited:  map[uintptr]bool{},
+	}
+	if d.maxDepth <= 0 {
+		d.maxDepth = defaultDumpMaxDepth
+	}
+
+	value := reflect.ValueOf(v)
+	if ptr, ok := pointer(value); ok {
+		d.visited[ptr] = true
+	}
+
+	children, ok := d.children(value)
+	switch {
+	case ok && len(children) == 0 && kvl.machineReadable():
+		return kvl.formatPairs([]kvPair{{"value", emptyContainer(indirect(value))}})
+	case ok && len(children) == 0:
+		d.b.WriteString(d.value(emptyContainer(indirect(value))) + "\n")
+	case ok && kvl.machineReadable():
+		d.walk(children, 0, "")
+		return kvl.formatPairs(d.pairs)
+	case ok:
+		d.walk(children, 0, "")
+	case kvl.machineReadable():
+		return kvl.formatPairs([]kvPair{{"value", d.raw(value)}})
+	default:
+		d.b.WriteString(d.value(d.scalar(value)) + "\n")
+	}
+	return d.b.String()
+}
+
+type dumper struct {
+	kvl      *KVLogger
+	colors   bool
+	b        *strings.Builder
+	maxDepth int
+	visited  map[uintptr]bool
+
+	// pairs are collected in place of the lines
+	// in the machine readable formats.
+	pairs []kvPair
+}
+
+// dumpNode is a named child of a struct, map or slice.
+type dumpNode struct {
+	key    string
+	value  reflect.Value
+	secret bool
+}
+
+func (d *dumper) walk(nodes []dumpNode, depth int, path string) {
+	for _, node := range nodes {
+		indent := strings.Repeat(dumpIndent, depth)
+		key := joinKey(path, node.key)
+
+		if node.secret {
+			d.line(indent, node.key, key, d.mask(node.value))
+			continue
+		}
+
+		value := indirect(node.value)
+		children, isContainer := d.children(value)
+		switch {
+		case !isContainer:
+			d.line(indent, node.key, key, d.raw(value))
+		case len(children) == 0:
+			d.line(indent, node.key, key, emptyContainer(value))
+		case depth+1 >= d.maxDepth:
+			d.line(indent, node.key, key, "...")
+		default:
+			ptr, isRef := pointer(node.value)
+			if isRef && d.visited[ptr] {
+				d.line(indent, node.key, key, "<cycle>")
+				continue
+			}
+			if isRef {
+				d.visited[ptr] = true
+			}
+
+			if !d.kvl.machineReadable() {
+				d.b.WriteString(indent + d.key(node.key, -1) + "\n")
+			}
+			d.walk(children, depth+1, key)
+
+			if isRef {
+				delete(d.visited, ptr)
+			}
+		}
+	}
+}
+
+// line writes a key-value pair, the key column width is reduced by the indent
+// so that the values stay aligned.
+// In the machine readable formats the pair is collected with its full path.
+func (d *dumper) line(indent, key, path string, value interface{}) {
+	if d.kvl.machineReadable() {
+		d.pairs = append(d.pairs, kvPair{path, value})
+		return
+	}
+	d.b.WriteString(indent + d.key(key, len(indent)) + d.value(fmt.Sprint(value)) + "\n")
+}
+
+// key pads the key to the column width minus indent, a negative indent
+// means no padding.
+func (d *dumper) key(key string, indent int) string {
+	width := d.kvl.keyWidth()
+	if indent >= 0 {
+		if VisibleWidth(key) >= width-indent {
+			key += " "
+		} else {
+			key = PadRight(key, width-indent)
+		}
+	}
+	return d.kvl.paintKey(key, d.colors)
+}
+
+func (d *dumper) value(value string) string {
+	return d.kvl.paintValue(value, d.colors)
+}
+
+// children returns the named children of structs, maps and slices.
+func (d *dumper) children(value reflect.Value) ([]dumpNode, bool) {
+	value = indirect(value)
+	if !value.IsValid() || isScalarType(value) {
+		return nil, false
+	}
+
+	var nodes []dumpNode
+
+	switch value.Kind() {
+	case reflect.Struct:
+		nodes = structNodes(value)
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessKey(keys[i], keys[j])
+		})
+		for _, k := range keys {
+			nodes = append(nodes, dumpNode{key: fmt.Sprint(k.Interface()), value: value.MapIndex(k)})
+		}
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false // []byte
+		}
+		for i := 0; i < value.Len(); i++ {
+			nodes = append(nodes, dumpNode{key: fmt.Sprintf("[%d]", i), value: value.Index(i)})
+		}
+	default:
+		return nil, false
+	}
+
+	return nodes, true
+}
+
+// lessKey orders the map keys, numbers of the same kind are compared
+// numerically, any other key by its string representation.
+func lessKey(a, b reflect.Value) bool {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+
+	switch {
+	case isIntKind(a.Kind()) && isIntKind(b.Kind()):
+		return a.Int() < b.Int()
+	case isUintKind(a.Kind()) && isUintKind(b.Kind()):
+		return a.Uint() < b.Uint()
+	case isFloatKind(a.Kind()) && isFloatKind(b.Kind()):
+		return a.Float() < b.Float()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uintptr
+}
+
+func isFloatKind(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// structNodes returns the exported fields of a struct,
+// embedded structs without a name are inlined.
+func structNodes(value reflect.Value) (nodes []dumpNode) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 && !field.Anonymous { // unexported
+			continue
+		}
+
+		options := strings.Split(field.Tag.Get("ansilog"), ",")
+		if options[0] == "-" {
+			continue
+		}
+
+		name, skip := fieldName(field)
+		if skip {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if field.Anonymous && len(name) == 0 {
+			if embedded := indirect(fieldValue); embedded.IsValid() && embedded.Kind() == reflect.Struct {
+				nodes = append(nodes, structNodes(embedded)...)
+			}
+			continue
+		}
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		node := dumpNode{key: name, value: fieldValue}
+		for _, option := range options {
+			if option == "secret" {
+				node.secret = true
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// fieldName returns the yaml or json name of the field.
+func fieldName(field reflect.StructField) (name string, skip bool) {
+	for _, tag := range []string{"yaml", "json"} {
+		value, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		name = strings.Split(value, ",")[0]
+		if name == "-" {
+			return "", true
+		}
+		if len(name) > 0 {
+			return name, false
+		}
+	}
+	return "", false
+}
+
+// raw returns the scalar value as is, so that numbers and booleans
+// keep their type in the json format.
+func (d *dumper) raw(value reflect.Value) interface{} {
+	if value.IsValid() && value.CanInterface() {
+		switch value.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64, reflect.String:
+			return value.Interface()
+		}
+	}
+	return d.scalar(value)
+}
+
+func (d *dumper) scalar(value reflect.Value) string {
+	if !value.IsValid() {
+		return "<nil>"
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if value.IsNil() {
+			return "<nil>"
+		}
+	}
+	if value.CanInterface() {
+		return fmt.Sprint(value.Interface())
+	}
+	return fmt.Sprint(value)
+}
+
+func (d *dumper) mask(value reflect.Value) string {
+	if value = indirect(value); !value.IsValid() || value.IsZero() {
+		return ""
+	}
+	return dumpMask
+}
+
+func emptyContainer(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "[]"
+	default:
+		return "{}"
+	}
+}
+
+// isScalarType reports whether the value must be printed as is
+// instead of being walked.
+func isScalarType(value reflect.Value) bool {
+	if value.Type() == reflect.TypeOf(time.Time{}) {
+		return true
+	}
+	if value.CanInterface() {
+		switch value.Interface().(type) {
+		case fmt.Stringer, error:
+			return true
+		}
+	}
+	return false
+}
+
+// indirect dereferences pointers and interfaces.
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// pointer returns the address of pointers, maps and slices, used for cycle detection.
+func pointer(value reflect.Value) (uintptr, bool) {
+	for value.IsValid() && value.Kind() == reflect.Interface && !value.IsNil() {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !value.IsNil() {
+			return value.Pointer(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,137 @@
+package ansilog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// KVLogger output formats.
+const (
+	// KVFormatText is the colored and padded human readable format.
+	KVFormatText = "text"
+	// KVFormatJSON prints a JSON object per line.
+	KVFormatJSON = "json"
+	// KVFormatLogfmt prints space separated key=value pairs per line.
+	KVFormatLogfmt = "logfmt"
+	// KVFormatEnv prints a KEY=value pair per line, as in .env files.
+	KVFormatEnv = "env"
+)
+
+// kvPair is a key-value pair in the machine readable formats,
+// nested keys are joined with a dot.
+type kvPair struct {
+	key   string
+	value interface{}
+}
+
+func validKVFormat(format string) bool {
+	switch format {
+	case "", KVFormatText, KVFormatJSON, KVFormatLogfmt, KVFormatEnv:
+		return true
+	}
+	return false
+}
+
+// machineReadable reports whether the KVLogger prints a machine readable format.
+func (kvl *KVLogger) machineReadable() bool {
+	return len(kvl.Format) > 0 && kvl.Format != KVFormatText
+}
+
+// formatPairs renders the pairs in the configured machine readable format,
+// the result always ends with a new line.
+func (kvl *KVLogger) formatPairs(pairs []kvPair) string {
+	b := &strings.Builder{}
+
+	switch kvl.Format {
+	case KVFormatJSON:
+		b.WriteByte('{')
+		for i, pair := range pairs {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Quote(pair.key) + ":" + jsonValue(pair.value))
+		}
+		b.WriteString("}\n")
+	case KVFormatLogfmt:
+		for i, pair := range pairs {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(logfmtKey(pair.key) + "=" + formatValue(stringValue(pair.value)))
+		}
+		b.WriteByte('\n')
+	case KVFormatEnv:
+		for _, pair := range pairs {
+			b.WriteString(envKey(pair.key) + "=" + envValue(stringValue(pair.value)) + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// joinKey returns the path of a nested key, slice indexes are not dot separated.
+func joinKey(prefix, key string) string {
+	switch {
+	case len(prefix) == 0:
+		return key
+	case strings.HasPrefix(key, "["):
+		return prefix + key
+	default:
+		return prefix + "." + key
+	}
+}
+
+func jsonValue(value interface{}) string {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case fmt.Stringer:
+		value = v.String()
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(value))
+	}
+	return string(b)
+}
+
+func stringValue(value interface{}) string {
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(value)
+}
+
+// logfmtKey replaces the spaces and the reserved characters in key.
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// envKey returns key upper cased, with any non alphanumeric character
+// replaced by an underscore, eg.: "database.host" -> "DATABASE_HOST".
+func envKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, key)
+	return strings.Join(strings.FieldsFunc(key, func(r rune) bool { return r == '_' }), "_")
+}
+
+// envValue quotes the value when it contains spaces or special characters.
+func envValue(value string) string {
+	if len(value) == 0 || !needsQuoting(value) {
+		return value
+	}
+	return strconv.Quote(value)
+}
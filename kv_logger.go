@@ -2,22 +2,73 @@ package ansilog
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/oblq/swap"
 )
 
 type KVConfig struct {
+	// Theme is the name of a built-in or registered theme
+	// or the path of a YAML theme file, its Key and Value colors are used
+	// unless KeyColor and ValColor are set.
+	Theme string `yaml:"theme"`
+
+	// KeyColor and ValColor are color names, eg.: "blue", "light-red", "bold #ff8800".
+	// See ParseColor for the allowed values.
 	KeyColor       string `yaml:"key_color"`
 	KeyMinColWidth int    `yaml:"key_col_width"`
 	ValColor       string `yaml:"val_color"`
+
+	// Format is the output format: text, json, logfmt or env.
+	// Optional. Default value is text.
+	Format string `yaml:"format"`
+
+	// ColorsMode determine if colors must be used or not.
+	// Optional. Default value is auto, colors are used
+	// only if Out is a terminal.
+	ColorsMode ConsoleColorsModeEnum `yaml:"colors_mode"`
+
+	// Out is a writer where pairs are written.
+	// Optional. Default value is os.Stdout.
+	Out io.Writer `yaml:"-"`
 }
 
 // KVLogger is the ansilog instance type for Key-Value logging.
+// It is safe for concurrent use, every pair is written with a single Write call.
 type KVLogger struct {
+	// KeyPainter and ValuePainter paint keys and values
+	// when colors are enabled, see ColorsMode.
 	KeyPainter     Painter
 	KeyMinColWidth int
 	ValuePainter   Painter
+
+	// Format is the output format: text, json, logfmt or env,
+	// colors and padding are used by the text format only.
+	Format string
+
+	// ColorsMode determine if colors must be used or not,
+	// it is resolved against Out or, for the F* funcs, against their writer.
+	ColorsMode ConsoleColorsModeEnum
+
+	// Out is a writer where pairs are written.
+	// Optional. Default value is os.Stdout.
+	Out io.Writer
+
+	// Theme is used to paint the StatusSummary footer.
+	// Default value is DefaultTheme.
+	Theme *Theme
+
+	// DumpMaxDepth is the maximum depth walked by Dump.
+	// Optional. Default value is 8.
+	DumpMaxDepth int
+
+	mutex        sync.Mutex
+	statusCounts [3]int
+	colors       colorsCache
 }
 
 func NewKVLogger(configFilePath string, config *KVConfig) *KVLogger {
@@ -27,10 +78,14 @@ func NewKVLogger(configFilePath string, config *KVConfig) *KVLogger {
 		}
 	}
 
-	kvl := &KVLogger{}
-	kvl.KeyPainter = NewPainter(color(config.KeyColor))
-	kvl.KeyMinColWidth = config.KeyMinColWidth
-	kvl.ValuePainter = NewPainter(color(config.ValColor))
+	if config == nil {
+		config = &KVConfig{}
+	}
+
+	kvl := &KVLogger{Out: config.Out}
+	if err := kvl.configure(config); err != nil {
+		panic(err.Error())
+	}
 
 	return kvl
 }
@@ -42,48 +97,142 @@ func (kvl *KVLogger) SpareConfig(configFiles []string) (err error) {
 		return err
 	}
 
-	kvl.KeyPainter = NewPainter(color(config.KeyColor))
+	return kvl.configure(config)
+}
+
+// configure set the painters from the theme and the configured color names.
+func (kvl *KVLogger) configure(config *KVConfig) error {
+	theme, err := resolveTheme(config.Theme)
+	if err != nil {
+		return err
+	}
+
+	keyColor, valColor := theme.Key, theme.Value
+
+	if len(config.KeyColor) > 0 {
+		if keyColor, err = ParseColor(config.KeyColor); err != nil {
+			return fmt.Errorf("invalid key_color: %v", err)
+		}
+	}
+
+	if len(config.ValColor) > 0 {
+		if valColor, err = ParseColor(config.ValColor); err != nil {
+			return fmt.Errorf("invalid val_color: %v", err)
+		}
+	}
+
+	if !validKVFormat(config.Format) {
+		return fmt.Errorf("invalid format: %s", config.Format)
+	}
+
+	kvl.KeyPainter = NewPainter(keyColor)
 	kvl.KeyMinColWidth = config.KeyMinColWidth
-	kvl.ValuePainter = NewPainter(color(config.ValColor))
+	kvl.ValuePainter = NewPainter(valColor)
+	kvl.Format = config.Format
+	kvl.ColorsMode = config.ColorsMode
+	kvl.Theme = theme
 
-	return
+	return nil
 }
 
 // maxColWidth define the KeyMaxColWidth default value.
 var minColWidth = 20
 
+// keyWidth returns the KeyMinColWidth or its default value.
+func (kvl *KVLogger) keyWidth() int {
+	if kvl.KeyMinColWidth == 0 {
+		return minColWidth
+	}
+	return kvl.KeyMinColWidth
+}
+
 // Print print the key with predefined KeyColor and width
 // and the value with the predefined ValueColor.
 func (kvl *KVLogger) Print(key interface{}, value interface{}) {
-	k, v := kvl.ansify(key, value)
-	fmt.Printf("%v%v", k, v)
+	kvl.Fprint(kvl.out(), key, value)
 }
 
 // Println print the key with predefined KeyColor and KeyMaxWidth
 // and the value with the predefined ValueColor.
 func (kvl *KVLogger) Println(key interface{}, value interface{}) {
-	k, v := kvl.ansify(key, value)
-	fmt.Printf("%v%v\n", k, v)
+	kvl.Fprintln(kvl.out(), key, value)
 }
 
-func (kvl *KVLogger) ansify(key interface{}, value interface{}) (string, string) {
-	if kvl.KeyMinColWidth == 0 {
-		kvl.KeyMinColWidth = minColWidth
-	}
+// Fprint is like Print but writes to w,
+// colors are used if enabled for w.
+func (kvl *KVLogger) Fprint(w io.Writer, key interface{}, value interface{}) {
+	kvl.write(w, kvl.sprint(key, value, kvl.colorsEnabledFor(w)))
+}
 
-	var k, v string
+// Fprintln is like Println but writes to w,
+// colors are used if enabled for w.
+func (kvl *KVLogger) Fprintln(w io.Writer, key interface{}, value interface{}) {
+	kvl.write(w, kvl.sprint(key, value, kvl.colorsEnabledFor(w))+"\n")
+}
+
+// Sprint is like Print but returns the resulting string.
+func (kvl *KVLogger) Sprint(key interface{}, value interface{}) string {
+	return kvl.sprint(key, value, kvl.colorsEnabled())
+}
 
-	if kvl.KeyPainter == nil {
-		k = fmt.Sprintf("%-"+strconv.Itoa(kvl.KeyMinColWidth)+"v", key)
-	} else {
-		k = kvl.KeyPainter(fmt.Sprintf("%-"+strconv.Itoa(kvl.KeyMinColWidth)+"v", key))
+func (kvl *KVLogger) sprint(key interface{}, value interface{}, colors bool) string {
+	if kvl.machineReadable() {
+		return strings.TrimSuffix(kvl.formatPairs([]kvPair{{fmt.Sprint(key), value}}), "\n")
 	}
 
-	if kvl.ValuePainter != nil {
-		v = kvl.ValuePainter(value)
-	} else {
-		v = fmt.Sprint(value)
+	k, v := kvl.ansify(key, value, colors)
+	return k + v
+}
+
+// Sprintln is like Println but returns the resulting string.
+func (kvl *KVLogger) Sprintln(key interface{}, value interface{}) string {
+	return kvl.Sprint(key, value) + "\n"
+}
+
+// colorsEnabled resolve the ColorsMode against Out.
+func (kvl *KVLogger) colorsEnabled() bool {
+	return kvl.colorsEnabledFor(kvl.out())
+}
+
+// colorsEnabledFor resolve the ColorsMode against w,
+// the result is cached until ColorsMode or the writer change.
+func (kvl *KVLogger) colorsEnabledFor(w io.Writer) bool {
+	return kvl.colors.enabled(kvl.ColorsMode, w)
+}
+
+func (kvl *KVLogger) out() io.Writer {
+	if kvl.Out == nil {
+		return os.Stdout
 	}
+	return kvl.Out
+}
+
+// write writes s with a single call, holding the lock,
+// so that concurrent lines are never interleaved.
+func (kvl *KVLogger) write(w io.Writer, s string) {
+	kvl.mutex.Lock()
+	defer kvl.mutex.Unlock()
+	_, _ = io.WriteString(w, s)
+}
 
+func (kvl *KVLogger) ansify(key interface{}, value interface{}, colors bool) (string, string) {
+	k := kvl.paintKey(fmt.Sprintf("%-"+strconv.Itoa(kvl.keyWidth())+"v", key), colors)
+	v := kvl.paintValue(value, colors)
 	return k, v
 }
+
+// paintKey paints key with the KeyPainter if colors are used.
+func (kvl *KVLogger) paintKey(key string, colors bool) string {
+	if !colors || kvl.KeyPainter == nil {
+		return key
+	}
+	return kvl.KeyPainter(key)
+}
+
+// paintValue paints value with the ValuePainter if colors are used.
+func (kvl *KVLogger) paintValue(value interface{}, colors bool) string {
+	if !colors || kvl.ValuePainter == nil {
+		return fmt.Sprint(value)
+	}
+	return kvl.ValuePainter(value)
+}
@@ -0,0 +1,216 @@
+package ansilog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestKVLogger_Out(t *testing.T) {
+	out := &bytes.Buffer{}
+	kvl := NewKVLogger("", &KVConfig{Out: out, KeyMinColWidth: 6, Theme: "monochrome"})
+	kvl.KeyPainter = nil
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			kvl.Println("key", "value")
+		}()
+	}
+	wg.Wait()
+
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if line != "key   value" {
+			t.Fatalf("unexpected line: %q", line)
+		}
+	}
+
+	if got := kvl.Sprint("k", 1); got != "k     1" {
+		t.Errorf("Sprint: got %q", got)
+	}
+}
+
+func TestKVLogger_Fprint(t *testing.T) {
+	defer clearColorsEnv()()
+
+	out, term := &bytes.Buffer{}, &bytes.Buffer{}
+	kvl := NewKVLogger("", &KVConfig{Out: out, KeyMinColWidth: 4})
+
+	// term is resolved as a terminal
+	kvl.colors.resolved, kvl.colors.out, kvl.colors.colors = true, term, true
+
+	kvl.Fprintln(term, "key", "value")
+	kvl.Fdump(term, map[string]int{"a": 1})
+	if got := term.String(); strings.Count(got, esc) != 4 {
+		t.Errorf("expected colors writing to a terminal: %q", got)
+	}
+
+	kvl.Println("key", "value")
+	kvl.Dump(map[string]int{"a": 1})
+	if got, want := out.String(), "key value\na   1\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestKVLogger_Dump(t *testing.T) {
+	type database struct {
+		Host     string `yaml:"host"`
+		Port     int    `json:"port"`
+		Password string `yaml:"password" ansilog:"secret"`
+		Ignored  string `yaml:"-"`
+	}
+	type node struct {
+		Name string
+		Next *node
+	}
+	config := struct {
+		Database database          `yaml:"database"`
+		Tags     []string          `yaml:"tags"`
+		Labels   map[string]string `yaml:"labels"`
+		Empty    []int             `yaml:"empty"`
+		Node     *node             `yaml:"node"`
+		internal string
+	}{
+		Database: database{Host: "localhost", Port: 5432, Password: "secret", Ignored: "x"},
+		Tags:     []string{"a", "b"},
+		Labels:   map[string]string{"z": "last", "a": "first"},
+		Node:     &node{Name: "root"},
+	}
+	config.Node.Next = config.Node
+
+	kvl := &KVLogger{KeyMinColWidth: 12}
+	want := "" +
+		"database\n" +
+		"  host      localhost\n" +
+		"  port      5432\n" +
+		"  password  ******\n" +
+		"tags\n" +
+		"  [0]       a\n" +
+		"  [1]       b\n" +
+		"labels\n" +
+		"  a         first\n" +
+		"  z         last\n" +
+		"empty       []\n" +
+		"node\n" +
+		"  Name      root\n" +
+		"  Next      <cycle>\n"
+	if got := kvl.Sdump(config); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	for _, tt := range []struct {
+		v    interface{}
+		want string
+	}{
+		{map[string]int(nil), "{}\n"},
+		{[]int{}, "[]\n"},
+		{&struct{}{}, "{}\n"},
+		{map[int]string{10: "c", 2: "b", 1: "a"}, "1           a\n2           b\n10          c\n"},
+	} {
+		if got := kvl.Sdump(tt.v); got != tt.want {
+			t.Errorf("%#v: got %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestKVLogger_Section(t *testing.T) {
+	kvl := &KVLogger{KeyMinColWidth: 8}
+	got := kvl.SprintSection("Database", func(s *Section) {
+		s.Add("host", "localhost")
+		s.Add("port", 5432)
+		s.Section("Pool", func(s *Section) {
+			s.Add("max_connections", 10)
+		})
+	})
+
+	want := "" +
+		"Database\n" +
+		"  host              localhost\n" +
+		"  port              5432\n" +
+		"  Pool\n" +
+		"    max_connections 10\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestKVLogger_Format(t *testing.T) {
+	config := struct {
+		Host   string   `yaml:"host"`
+		Port   int      `yaml:"port"`
+		Debug  bool     `yaml:"debug"`
+		Tags   []string `yaml:"tags"`
+		Secret string   `yaml:"secret" ansilog:"secret"`
+	}{"localhost", 5432, true, []string{"a b"}, "x"}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{KVFormatJSON, `{"host":"localhost","port":5432,"debug":true,"tags[0]":"a b","secret":"******"}` + "\n"},
+		{KVFormatLogfmt, `host=localhost port=5432 debug=true tags[0]="a b" secret="******"` + "\n"},
+		{KVFormatEnv, "HOST=localhost\nPORT=5432\nDEBUG=true\nTAGS_0=\"a b\"\nSECRET=\"******\"\n"},
+	}
+
+	for _, tt := range tests {
+		kvl := NewKVLogger("", &KVConfig{Format: tt.format})
+		if got := kvl.Sdump(config); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	kvl := NewKVLogger("", &KVConfig{Format: KVFormatEnv})
+	got := kvl.SprintSection("Database", func(s *Section) { s.Add("max conns", 10) })
+	if want := "DATABASE_MAX_CONNS=10\n"; got != want {
+		t.Errorf("section: got %q, want %q", got, want)
+	}
+	if got, want := kvl.Sprintln("app.name", "api"), "APP_NAME=api\n"; got != want {
+		t.Errorf("Sprintln: got %q, want %q", got, want)
+	}
+}
+
+func TestKVLogger_Status(t *testing.T) {
+	out := &bytes.Buffer{}
+	kvl := NewKVLogger("", &KVConfig{Out: out, KeyMinColWidth: 16, ColorsMode: ConsoleColorsModeDisabled})
+
+	kvl.Status("postgres", StatusOK, "connected")
+	kvl.Status("redis", StatusFail, nil)
+	kvl.Status("a very long key name", StatusWarn, "slow")
+	kvl.StatusSummary()
+
+	want := "" +
+		"postgres ...... [ OK ] connected\n" +
+		"redis ......... [FAIL]\n" +
+		"a very long key name [WARN] slow\n" +
+		"1 passed, 1 warning, 1 failed\n"
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	kvl.ColorsMode = ConsoleColorsModeEnabled
+	if got := kvl.SprintStatus("redis", StatusFail, nil); !strings.Contains(got, BgRed(" FAIL ")) {
+		t.Errorf("unexpected badge: %q", got)
+	}
+}
+
+func TestKVLogger_StatusSummaryTheme(t *testing.T) {
+	out := &bytes.Buffer{}
+	kvl := NewKVLogger("", &KVConfig{Out: ioutil.Discard, Theme: "monochrome", ColorsMode: ConsoleColorsModeEnabled})
+
+	kvl.Status("a", StatusOK, nil)
+	kvl.Status("b", StatusWarn, nil)
+	kvl.Status("c", StatusFail, nil)
+	kvl.Out = out
+	kvl.StatusSummary()
+
+	if got, want := out.String(), "1 passed, \033[1m1 warning\033[0m, \033[1m1 failed\033[0m\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if ok, warn, fail := kvl.StatusCounts(); ok+warn+fail != 0 {
+		t.Errorf("the counters must be reset, got %d %d %d", ok, warn, fail)
+	}
+}
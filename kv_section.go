@@ -0,0 +1,118 @@
+package ansilog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Section is a group of key-value pairs rendered together
+// under a header, see KVLogger.Section.
+type Section struct {
+	title string
+	rows  []sectionRow
+}
+
+type sectionRow struct {
+	key     string
+	value   interface{}
+	section *Section
+}
+
+// Add adds a key-value pair to the section.
+func (s *Section) Add(key interface{}, value interface{}) {
+	s.rows = append(s.rows, sectionRow{key: fmt.Sprint(key), value: value})
+}
+
+// Section adds a nested section, its rows are indented.
+func (s *Section) Section(title string, fn func(s *Section)) {
+	sub := &Section{title: title}
+	fn(sub)
+	s.rows = append(s.rows, sectionRow{section: sub})
+}
+
+// Section buffers the pairs added by fn and prints them at once,
+// under the title header.
+// The key column is sized on the widest key (KeyMinColWidth is the minimum),
+// so that all the values, nested sections included, are aligned:
+//
+//	kvl.Section("Database", func(s *ansilog.Section) {
+//		s.Add("host", config.Host)
+//		s.Section("Pool", func(s *ansilog.Section) {
+//			s.Add("max_connections", config.MaxConns)
+//		})
+//	})
+func (kvl *KVLogger) Section(title string, fn func(s *Section)) {
+	kvl.write(kvl.out(), kvl.SprintSection(title, fn))
+}
+
+// SprintSection is like Section but returns the resulting string.
+func (kvl *KVLogger) SprintSection(title string, fn func(s *Section)) string {
+	section := &Section{title: title}
+	fn(section)
+
+	if kvl.machineReadable() {
+		return kvl.formatPairs(section.pairs(""))
+	}
+
+	width := kvl.keyWidth()
+	if w := section.keyWidth(0) + 1; w > width {
+		width = w
+	}
+
+	b := &strings.Builder{}
+	kvl.renderSection(b, section, 0, width, kvl.colorsEnabled())
+	return b.String()
+}
+
+// pairs returns the flattened pairs, keys are prefixed by the section titles.
+func (s *Section) pairs(path string) (pairs []kvPair) {
+	path = joinKey(path, s.title)
+	for _, row := range s.rows {
+		if row.section != nil {
+			pairs = append(pairs, row.section.pairs(path)...)
+		} else {
+			pairs = append(pairs, kvPair{joinKey(path, row.key), row.value})
+		}
+	}
+	return pairs
+}
+
+// keyWidth returns the widest key plus its indent.
+func (s *Section) keyWidth(indent int) (width int) {
+	for _, row := range s.rows {
+		w := 0
+		if row.section != nil {
+			w = row.section.keyWidth(indent + len(dumpIndent))
+		} else {
+			w = indent + len(dumpIndent) + VisibleWidth(row.key)
+		}
+		if w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+func (kvl *KVLogger) renderSection(b *strings.Builder, s *Section, depth int, width int, colors bool) {
+	indent := strings.Repeat(dumpIndent, depth)
+	b.WriteString(indent + kvl.header(s.title, colors) + "\n")
+
+	indent += dumpIndent
+	for _, row := range s.rows {
+		if row.section != nil {
+			kvl.renderSection(b, row.section, depth+1, width, colors)
+			continue
+		}
+
+		key := kvl.paintKey(PadRight(row.key, width-len(indent)), colors)
+		value := kvl.paintValue(row.value, colors)
+		b.WriteString(indent + key + value + "\n")
+	}
+}
+
+func (kvl *KVLogger) header(title string, colors bool) string {
+	if kvl.KeyPainter == nil || !colors {
+		return title
+	}
+	return kvl.KeyPainter(NewStyle().Bold().Paint(title))
+}
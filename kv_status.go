@@ -0,0 +1,135 @@
+package ansilog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatusLevel is the result of a check printed by KVLogger.Status.
+type StatusLevel int
+
+const (
+	StatusOK StatusLevel = iota
+	StatusWarn
+	StatusFail
+)
+
+func (s StatusLevel) String() string {
+	switch s {
+	case StatusOK:
+		return "OK"
+	case StatusWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// badgeColor returns the background color of the status badge.
+func (s StatusLevel) badgeColor() Color {
+	switch s {
+	case StatusOK:
+		return badgeGreen
+	case StatusWarn:
+		return badgeYellow
+	default:
+		return badgeRed
+	}
+}
+
+// Status prints a status-tagged line, eg.: "postgres ........  OK  connected",
+// the key is followed by a dotted leader up to the key column
+// and the status is rendered as a colored badge.
+// Statuses are counted, see StatusSummary.
+func (kvl *KVLogger) Status(key interface{}, status StatusLevel, detail interface{}) {
+	if status < StatusOK || status > StatusFail {
+		status = StatusFail
+	}
+
+	kvl.mutex.Lock()
+	kvl.statusCounts[status]++
+	kvl.mutex.Unlock()
+
+	kvl.write(kvl.out(), kvl.SprintStatus(key, status, detail))
+}
+
+// SprintStatus is like Status but returns the resulting string,
+// the status is not counted.
+func (kvl *KVLogger) SprintStatus(key interface{}, status StatusLevel, detail interface{}) string {
+	k := fmt.Sprint(key)
+
+	if kvl.machineReadable() {
+		pairs := []kvPair{{k, status.String()}}
+		if detail != nil {
+			pairs = append(pairs, kvPair{joinKey(k, "detail"), detail})
+		}
+		return kvl.formatPairs(pairs)
+	}
+
+	width := kvl.keyWidth()
+
+	leader := " "
+	if pad := width - VisibleWidth(k) - 1; pad > 1 {
+		leader = " " + strings.Repeat(".", pad-1) + " "
+	}
+	colors := kvl.colorsEnabled()
+
+	line := kvl.paintKey(k, colors) + leader + kvl.badge(status)
+	if detail != nil {
+		line += " " + kvl.paintValue(detail, colors)
+	}
+	return line + "\n"
+}
+
+// StatusCounts returns the number of statuses printed so far, by level.
+func (kvl *KVLogger) StatusCounts() (ok, warn, fail int) {
+	kvl.mutex.Lock()
+	defer kvl.mutex.Unlock()
+	return kvl.statusCounts[StatusOK], kvl.statusCounts[StatusWarn], kvl.statusCounts[StatusFail]
+}
+
+// StatusSummary prints a footer with the number of passed, warning and failed statuses,
+// eg.: "3 passed, 1 warning, 1 failed", then resets the counters.
+// The counts are painted with the Theme Status2xx, Warn and Error colors.
+func (kvl *KVLogger) StatusSummary() {
+	kvl.mutex.Lock()
+	ok, warn, fail := kvl.statusCounts[StatusOK], kvl.statusCounts[StatusWarn], kvl.statusCounts[StatusFail]
+	kvl.statusCounts = [3]int{}
+	kvl.mutex.Unlock()
+
+	if kvl.machineReadable() {
+		kvl.write(kvl.out(), kvl.formatPairs([]kvPair{{"passed", ok}, {"warnings", warn}, {"failed", fail}}))
+		return
+	}
+
+	theme := themeOrDefault(kvl.Theme)
+	colors := kvl.colorsEnabled()
+	parts := []string{paint(theme.Status2xx, fmt.Sprintf("%d passed", ok), colors)}
+	if warn > 0 {
+		parts = append(parts, paint(theme.Warn, fmt.Sprintf("%d %s", warn, plural(warn, "warning")), colors))
+	}
+	failed := fmt.Sprintf("%d failed", fail)
+	if fail > 0 {
+		failed = paint(theme.Error, failed, colors)
+	}
+	parts = append(parts, failed)
+
+	kvl.write(kvl.out(), strings.Join(parts, ", ")+"\n")
+}
+
+// badge returns the status as a colored badge or, if colors are disabled,
+// between square brackets.
+func (kvl *KVLogger) badge(status StatusLevel) string {
+	label := Center(status.String(), 4)
+	if !kvl.colorsEnabled() {
+		return "[" + label + "]"
+	}
+	return colored(" "+label+" ", status.badgeColor())
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
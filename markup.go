@@ -0,0 +1,119 @@
+package ansilog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MarkupColorsMode determine if Sprintf, Printf and Fprintf must use colors.
+// Sprintf and Printf resolve it against os.Stdout,
+// Fprintf against the given writer.
+var MarkupColorsMode = ConsoleColorsModeAuto
+
+// Sprintf formats according to a format specifier and renders the markup tags,
+// eg.: Sprintf("[bold red]error[/] in [cyan]%s[/]", file).
+// Tags are space separated color and attribute names (see ParseColor),
+// [/] closes the last opened tag and tags can be nested.
+// Use [[ and ]] for literal brackets.
+// Markup is rendered before formatting, so arguments are never parsed as tags.
+func Sprintf(format string, a ...interface{}) string {
+	return fmt.Sprintf(RenderMarkup(format, MarkupColorsMode.Enabled(os.Stdout)), a...)
+}
+
+// Printf is like Sprintf but prints to os.Stdout.
+func Printf(format string, a ...interface{}) (n int, err error) {
+	return Fprintf(os.Stdout, format, a...)
+}
+
+// Fprintf is like Sprintf but writes to w,
+// colors are used only if MarkupColorsMode is enabled for w.
+func Fprintf(w io.Writer, format string, a ...interface{}) (n int, err error) {
+	return fmt.Fprintf(w, RenderMarkup(format, MarkupColorsMode.Enabled(w)), a...)
+}
+
+// RenderMarkup replace the markup tags in s with the corresponding escape codes,
+// or removes them if colors is false.
+// Unknown tags and unmatched [/] are left untouched.
+func RenderMarkup(s string, colors bool) string {
+	var b strings.Builder
+	var stack []Color
+
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "[["):
+			b.WriteByte('[')
+			i += 2
+			continue
+		case strings.HasPrefix(s[i:], "]]"):
+			b.WriteByte(']')
+			i += 2
+			continue
+		case s[i] != '[':
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], ']')
+		if end < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+		tag := s[i+1 : i+end]
+
+		// an unmatched [/] is left untouched, as the unknown tags.
+		if tag == "/" && len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+			if colors {
+				b.WriteString(clear)
+				for _, c := range stack {
+					b.WriteString(esc + string(c))
+				}
+			}
+			i += end + 1
+			continue
+		}
+
+		c, ok := parseMarkupTag(tag)
+		if !ok {
+			b.WriteByte('[')
+			i++
+			continue
+		}
+
+		stack = append(stack, c)
+		if colors {
+			b.WriteString(esc + string(c))
+		}
+		i += end + 1
+	}
+
+	if colors && len(stack) > 0 {
+		b.WriteString(clear)
+	}
+	return b.String()
+}
+
+// parseMarkupTag parse the color names in tag,
+// raw SGR codes are not allowed so that fmt argument indexes
+// such as %[1]s and plain text such as [2] are not treated as tags.
+func parseMarkupTag(tag string) (Color, bool) {
+	words := strings.Fields(tag)
+	if len(words) == 0 {
+		return "", false
+	}
+
+	for _, word := range words {
+		if isSGR(strings.TrimSuffix(word, "m")) {
+			return "", false
+		}
+	}
+
+	c, err := ParseColor(tag)
+	if err != nil || len(c) == 0 {
+		return "", false
+	}
+	return c, true
+}
@@ -0,0 +1,37 @@
+package ansilog
+
+import "testing"
+
+func TestRenderMarkup(t *testing.T) {
+	tests := []struct {
+		markup string
+		colors bool
+		want   string
+	}{
+		{"[red]a[/]", true, "\033[31ma\033[0m"},
+		{"[bold red]a[/]", true, "\033[1;31ma\033[0m"},
+		{"[blue]a [red]b[/] c[/]", true, "\033[34ma \033[31mb\033[0m\033[34m c\033[0m"},
+		{"[blue]unclosed", true, "\033[34munclosed\033[0m"},
+		{"[blue]a [red]b[/] c[/]", false, "a b c"},
+		{"[[red]] and [2] and [unknown]", true, "[red] and [2] and [unknown]"},
+		{"%[1]s", true, "%[1]s"},
+		{"[/] a [red]b", true, "[/] a \033[31mb\033[0m"},
+		{"[red]a[/][/]", false, "a[/]"},
+	}
+
+	for _, tt := range tests {
+		if got := RenderMarkup(tt.markup, tt.colors); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.markup, got, tt.want)
+		}
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	mode := MarkupColorsMode
+	defer func() { MarkupColorsMode = mode }()
+
+	MarkupColorsMode = ConsoleColorsModeDisabled
+	if got, want := Sprintf("[bold red]error[/] in [cyan]%s[/]", "[file]"), "error in [file]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
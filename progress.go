@@ -0,0 +1,382 @@
+package ansilog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress renders a group of progress bars and spinners
+// below the text written to the same terminal.
+//
+// Progress is an io.Writer, lines written to it (eg.: by a Logger, see Attach)
+// clear the bars, are printed and the bars are redrawn below them.
+// When the output is not a terminal (see IsTerm) the bars fall back to
+// plain-text status lines printed every StatusInterval.
+type Progress struct {
+	// Interval is the redraw interval on terminals.
+	// Default value is 100ms.
+	Interval time.Duration
+
+	// StatusInterval is the plain-text status interval on non terminals.
+	// Default value is 5s.
+	StatusInterval time.Duration
+
+	// BarWidth is the width of the bars, in cells.
+	// Default value is 30.
+	BarWidth int
+
+	// ColorsMode determine if colors must be used or not.
+	ColorsMode ConsoleColorsModeEnum
+
+	// Theme is used to paint the bars.
+	// Default value is DefaultTheme.
+	Theme *Theme
+
+	out   io.Writer
+	tty   bool
+	mutex sync.Mutex
+	items []progressItem
+	lines int
+	frame int
+
+	// last printed status of every item, non terminals only.
+	statuses map[progressItem]string
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Default redraw and status intervals,
+// used in place of the non positive Interval and StatusInterval.
+const (
+	defaultProgressInterval       = 100 * time.Millisecond
+	defaultProgressStatusInterval = 5 * time.Second
+)
+
+type progressItem interface {
+	render(p *Progress, colors bool) string
+	status() string
+	finished() bool
+}
+
+// NewProgress returns a new Progress writing to out.
+func NewProgress(out io.Writer) *Progress {
+	return &Progress{
+		Interval:       defaultProgressInterval,
+		StatusInterval: defaultProgressStatusInterval,
+		BarWidth:       30,
+		out:            out,
+		tty:            IsTerm(out),
+		statuses:       map[progressItem]string{},
+	}
+}
+
+// Attach set the Progress as the logger output,
+// so that log entries are printed above the bars.
+func (p *Progress) Attach(logger *Logger) {
+	logger.Logger.SetOutput(p)
+}
+
+// AddBar adds a progress bar, a total <= 0 makes it indeterminate.
+// The Progress is started automatically.
+func (p *Progress) AddBar(label string, total int64) *Bar {
+	bar := &Bar{label: label, total: total, start: time.Now()}
+	p.add(bar)
+	return bar
+}
+
+// AddSpinner adds a spinner.
+// The Progress is started automatically.
+func (p *Progress) AddSpinner(label string) *Spinner {
+	spinner := &Spinner{label: label, start: time.Now()}
+	p.add(spinner)
+	return spinner
+}
+
+func (p *Progress) add(item progressItem) {
+	p.mutex.Lock()
+	p.items = append(p.items, item)
+	p.mutex.Unlock()
+	p.Start()
+}
+
+// Start starts the redraw loop, it is a no-op if already started.
+func (p *Progress) Start() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stop != nil {
+		return
+	}
+
+	p.stop = make(chan struct{})
+	p.stopped = make(chan struct{})
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	if !p.tty {
+		interval = p.StatusInterval
+		if interval <= 0 {
+			interval = defaultProgressStatusInterval
+		}
+	}
+
+	go func(stop, stopped chan struct{}) {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				p.mutex.Lock()
+				p.frame++
+				if p.tty {
+					p.draw()
+				} else {
+					p.printStatuses(true)
+				}
+				p.mutex.Unlock()
+			}
+		}
+	}(p.stop, p.stopped)
+}
+
+// Stop stops the redraw loop and renders the final state,
+// the bars are left on screen and the next writes are printed below them.
+func (p *Progress) Stop() {
+	p.mutex.Lock()
+	stop, stopped := p.stop, p.stopped
+	p.stop, p.stopped = nil, nil
+	p.mutex.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-stopped
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.draw()
+	p.lines = 0
+	p.items = nil
+	p.statuses = map[progressItem]string{}
+}
+
+// Write clears the bars, writes b and redraws the bars below it.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.tty {
+		return p.out.Write(b)
+	}
+
+	p.clear()
+	n, err := p.out.Write(b)
+	// the bars are drawn on a new line, below a partial line.
+	if n > 0 && b[n-1] != '\n' {
+		_, _ = io.WriteString(p.out, "\n")
+	}
+	p.lines = 0
+	p.draw()
+	return n, err
+}
+
+// clear removes the drawn bars, terminals only.
+func (p *Progress) clear() {
+	if p.lines > 0 {
+		fmt.Fprintf(p.out, "\033[%dA\033[J", p.lines)
+		p.lines = 0
+	}
+}
+
+// draw redraws the bars on terminals
+// or prints the changed statuses on non terminals.
+func (p *Progress) draw() {
+	if !p.tty {
+		p.printStatuses(false)
+		return
+	}
+
+	colors := p.ColorsMode.Enabled(p.out)
+
+	b := &strings.Builder{}
+	if p.lines > 0 {
+		fmt.Fprintf(b, "\033[%dA\033[J", p.lines)
+	}
+	for _, item := range p.items {
+		b.WriteString(item.render(p, colors))
+		b.WriteByte('\n')
+	}
+	_, _ = io.WriteString(p.out, b.String())
+	p.lines = len(p.items)
+}
+
+// printStatuses prints the changed statuses, non terminals only,
+// periodic prints the statuses of the running items even if unchanged.
+func (p *Progress) printStatuses(periodic bool) {
+	for _, item := range p.items {
+		status := item.status()
+		if status == p.statuses[item] && (!periodic || item.finished()) {
+			continue
+		}
+		p.statuses[item] = status
+		fmt.Fprintln(p.out, status)
+	}
+}
+
+// Bar --------------------------------------------------------------------------------------------------------------
+
+// Bar is a determinate or indeterminate progress bar.
+type Bar struct {
+	label   string
+	total   int64
+	current int64
+	done    int32
+	start   time.Time
+}
+
+// Add increments the bar by n.
+func (b *Bar) Add(n int64) {
+	atomic.AddInt64(&b.current, n)
+}
+
+// Increment increments the bar by one.
+func (b *Bar) Increment() {
+	b.Add(1)
+}
+
+// Set set the bar current value.
+func (b *Bar) Set(n int64) {
+	atomic.StoreInt64(&b.current, n)
+}
+
+// SetTotal set the bar total, a total <= 0 makes it indeterminate.
+func (b *Bar) SetTotal(total int64) {
+	atomic.StoreInt64(&b.total, total)
+}
+
+// Finish marks the bar as completed.
+func (b *Bar) Finish() {
+	if total := atomic.LoadInt64(&b.total); total > 0 {
+		atomic.StoreInt64(&b.current, total)
+	}
+	atomic.StoreInt32(&b.done, 1)
+}
+
+func (b *Bar) render(p *Progress, colors bool) string {
+	theme := themeOrDefault(p.Theme)
+	current := atomic.LoadInt64(&b.current)
+	total := atomic.LoadInt64(&b.total)
+	done := atomic.LoadInt32(&b.done) == 1
+	elapsed := time.Since(b.start).Round(time.Second)
+
+	width := p.BarWidth
+	if width < 0 {
+		width = 0
+	}
+	var filled, empty string
+
+	if total > 0 {
+		n := int(int64(width) * current / total)
+		if n > width {
+			n = width
+		} else if n < 0 {
+			n = 0
+		}
+		filled = strings.Repeat("█", n)
+		empty = strings.Repeat("░", width-n)
+		return fmt.Sprintf("%s %s%s %3d%% %d/%d %s", b.label,
+			paint(theme.Info, filled, colors), paint(theme.Muted, empty, colors),
+			100*current/total, current, total, elapsed)
+	}
+
+	if done {
+		filled = strings.Repeat("█", width)
+		return fmt.Sprintf("%s %s %d %s", b.label, paint(theme.Info, filled, colors), current, elapsed)
+	}
+
+	// indeterminate: a block bouncing back and forth.
+	block := width / 5
+	if block < 1 {
+		block = 1
+	}
+	span := width - block
+	if span < 0 {
+		span = 0
+	}
+	position := p.frame % (2*span + 1)
+	if position > span {
+		position = 2*span - position
+	}
+	bar := paint(theme.Muted, strings.Repeat("░", position), colors) +
+		paint(theme.Info, strings.Repeat("█", block), colors) +
+		paint(theme.Muted, strings.Repeat("░", span-position), colors)
+	return fmt.Sprintf("%s %s %d %s", b.label, bar, current, elapsed)
+}
+
+func (b *Bar) finished() bool {
+	return atomic.LoadInt32(&b.done) == 1
+}
+
+func (b *Bar) status() string {
+	current := atomic.LoadInt64(&b.current)
+	total := atomic.LoadInt64(&b.total)
+	done := atomic.LoadInt32(&b.done) == 1
+
+	status := fmt.Sprintf("%s: %d", b.label, current)
+	if total > 0 {
+		status = fmt.Sprintf("%s: %d/%d (%d%%)", b.label, current, total, 100*current/total)
+	}
+	if done {
+		status += " done"
+	}
+	return status
+}
+
+// Spinner ----------------------------------------------------------------------------------------------------------
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Spinner is an activity indicator for tasks of unknown length.
+type Spinner struct {
+	label string
+	done  int32
+	start time.Time
+}
+
+// Finish marks the spinner as completed.
+func (s *Spinner) Finish() {
+	atomic.StoreInt32(&s.done, 1)
+}
+
+func (s *Spinner) render(p *Progress, colors bool) string {
+	elapsed := time.Since(s.start).Round(time.Second)
+	if atomic.LoadInt32(&s.done) == 1 {
+		return fmt.Sprintf("%s %s %s", paint(themeOrDefault(p.Theme).Info, "✓", colors), s.label, elapsed)
+	}
+	frame := spinnerFrames[p.frame%len(spinnerFrames)]
+	return fmt.Sprintf("%s %s %s", paint(themeOrDefault(p.Theme).Info, frame, colors), s.label, elapsed)
+}
+
+func (s *Spinner) finished() bool {
+	return atomic.LoadInt32(&s.done) == 1
+}
+
+func (s *Spinner) status() string {
+	if atomic.LoadInt32(&s.done) == 1 {
+		return s.label + ": done"
+	}
+	return s.label + ": running"
+}
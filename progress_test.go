@@ -0,0 +1,117 @@
+package ansilog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress_NonTerminal(t *testing.T) {
+	out := &bytes.Buffer{}
+	progress := NewProgress(out)
+	progress.ColorsMode = ConsoleColorsModeDisabled
+
+	logger, err := NewWithConfig(Config{Out: out, ColorsMode: ConsoleColorsModeDisabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress.Attach(logger)
+
+	bar := progress.AddBar("migrating", 10)
+	spinner := progress.AddSpinner("waiting")
+	bar.Add(4)
+	logger.Info("hello")
+	bar.Finish()
+	spinner.Finish()
+	progress.Stop()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{"msg=hello", "migrating: 10/10 (100%) done", "waiting: done"}
+	if len(lines) != len(want) {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	for i := range want {
+		if !strings.Contains(lines[i], want[i]) {
+			t.Errorf("line %d: got %q, want %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestProgress_Terminal(t *testing.T) {
+	out := &bytes.Buffer{}
+	progress := NewProgress(out)
+	progress.tty = true
+	progress.ColorsMode = ConsoleColorsModeDisabled
+	progress.Interval = time.Hour
+	defer progress.Stop()
+
+	bar := progress.AddBar("migrating", 10)
+	progress.AddBar("scanning", 0)
+	bar.Add(5)
+
+	_, _ = progress.Write([]byte("first\n"))
+	out.Reset()
+	_, _ = progress.Write([]byte("second\n"))
+
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) != 4 || lines[0] != "\033[2A\033[Jsecond" ||
+		!strings.HasPrefix(lines[1], "migrating "+strings.Repeat("█", 15)+strings.Repeat("░", 15)+"  50% 5/10") ||
+		!strings.HasPrefix(lines[2], "scanning ") {
+		t.Errorf("unexpected redraw: %q", out.String())
+	}
+
+	// negative values and widths must not panic
+	bar.Set(-1)
+	progress.BarWidth = -1
+	_, _ = progress.Write([]byte("third\n"))
+	progress.BarWidth = 0
+	_, _ = progress.Write([]byte("fourth\n"))
+}
+
+func TestProgress_NonTerminalInterval(t *testing.T) {
+	out := &bytes.Buffer{}
+	progress := NewProgress(out)
+	progress.StatusInterval = 10 * time.Millisecond
+
+	spinner := progress.AddSpinner("waiting")
+	time.Sleep(55 * time.Millisecond)
+	spinner.Finish()
+	progress.Stop()
+
+	if running := strings.Count(out.String(), "waiting: running\n"); running < 2 {
+		t.Errorf("the status must be printed every interval:\n%s", out.String())
+	}
+	if done := strings.Count(out.String(), "waiting: done\n"); done != 1 {
+		t.Errorf("the final status must be printed once:\n%s", out.String())
+	}
+}
+
+func TestProgress_PartialLine(t *testing.T) {
+	out := &bytes.Buffer{}
+	progress := NewProgress(out)
+	progress.tty = true
+	progress.ColorsMode = ConsoleColorsModeDisabled
+	progress.Interval = time.Hour
+	defer progress.Stop()
+
+	progress.AddSpinner("s")
+	_, _ = progress.Write([]byte("partial"))
+
+	if !strings.HasPrefix(out.String(), "partial\n") {
+		t.Errorf("the bars must be drawn below the partial line: %q", out.String())
+	}
+}
+
+func TestProgress_NonPositiveIntervals(t *testing.T) {
+	for _, tty := range []bool{true, false} {
+		progress := NewProgress(&bytes.Buffer{})
+		progress.tty = tty
+		progress.Interval = 0
+		progress.StatusInterval = -time.Second
+
+		// must not panic
+		progress.Start()
+		progress.Stop()
+	}
+}
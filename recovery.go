@@ -0,0 +1,100 @@
+package ansilog
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/oblq/ansilog/internal/hooks/stack_trace"
+	"github.com/sirupsen/logrus"
+)
+
+// RecoveryHandlerFunc writes the response of a request whose handler panicked,
+// recovered is the value passed to panic.
+type RecoveryHandlerFunc func(w http.ResponseWriter, r *http.Request, recovered interface{})
+
+// WithRecovery enables the panic recovery in the middlewares.
+// The panic is logged at error level with its stack-trace
+// (in the same "stack" field of the stack_trace hook),
+// then handler writes the response, by default a 500 Internal Server Error.
+// The access log is emitted with status 500 and marked as recovered,
+// also when the handler had already written the response headers,
+// the client then received the status written by the handler.
+// http.ErrAbortHandler is not recovered, as in net/http.
+func WithRecovery(handler RecoveryHandlerFunc) HttpTracerOption {
+	return func(hl *HttpTracer) {
+		hl.Recovery = true
+		hl.RecoveryHandler = handler
+	}
+}
+
+// recovered logs the recovered panic and writes the error response
+// with the RecoveryHandler or, if nil, with respond.
+// If the response has already been written or the connection
+// has been hijacked it can only be logged.
+func (hl *HttpTracer) recovered(w http.ResponseWriter, r *http.Request, rw *responseWriter,
+	recovered interface{}, respond func()) {
+
+	if recovered == http.ErrAbortHandler {
+		panic(recovered)
+	}
+
+	hl.logPanic(r, recovered, debug.Stack())
+	rw.trace.recovered = true
+
+	if rw.status != 0 || rw.trace.state == StateHijacked {
+		rw.status = http.StatusInternalServerError
+		return
+	}
+
+	if hl.RecoveryHandler != nil {
+		hl.RecoveryHandler(w, r, recovered)
+	}
+	if rw.status == 0 {
+		respond()
+	}
+}
+
+// logPanic logs the panic with the Logger backend or with the tracer logger,
+// in the json and logfmt formats as a single record.
+func (hl *HttpTracer) logPanic(r *http.Request, recovered interface{}, stack []byte) {
+	msg := fmt.Sprintf("panic recovered: %v", recovered)
+
+	if hl.logger == nil {
+		pairs := []kvPair{
+			{"time", time.Now().UTC().Format(time.RFC3339Nano)},
+			{"level", logrus.ErrorLevel.String()},
+			{"msg", msg},
+			{"panic", fmt.Sprint(recovered)},
+			{"method", r.Method},
+			{"path", r.URL.Path},
+		}
+		if id := RequestIDFromContext(r.Context()); len(id) > 0 {
+			pairs = append(pairs, kvPair{"request_id", id})
+		}
+		pairs = append(pairs, kvPair{stack_trace.StackKey, string(stack)})
+
+		switch strings.ToLower(hl.Format) {
+		case FormatterJSON:
+			hl.Println(logEntry(pairs).json())
+		case FormatterLogfmt:
+			hl.Println(logEntry(pairs).logfmt())
+		default:
+			hl.Println(hl.paint(themeOrDefault(hl.Theme).Status5xx, msg) + "\n" + string(stack))
+		}
+		return
+	}
+
+	hl.logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"method":             r.Method,
+		"path":               r.URL.Path,
+		stack_trace.StackKey: string(stack),
+	}).Error(msg)
+}
+
+// internalServerError is the default RecoveryHandler response.
+func internalServerError(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
@@ -0,0 +1,171 @@
+package ansilog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHttpTracer_Recovery(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, err := NewWithConfig(Config{Out: out, Formatter: FormatterConfig{Name: FormatterJSON}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	panicking := func(w http.ResponseWriter, r *http.Request) { panic("boom") }
+
+	newTracer := func(handler RecoveryHandlerFunc) *HttpTracer {
+		return NewHttpTracerWithOptions(WithLogger(logger), WithRecovery(handler))
+	}
+
+	adapters := map[string]func(tracer *HttpTracer) http.Handler{
+		"Handler":     func(tracer *HttpTracer) http.Handler { return tracer.Handler(http.HandlerFunc(panicking)) },
+		"HandlerFunc": func(tracer *HttpTracer) http.Handler { return tracer.HandlerFunc(panicking) },
+		"negroni": func(tracer *HttpTracer) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { tracer.ServeHTTP(w, r, panicking) })
+		},
+		"echo": func(tracer *HttpTracer) http.Handler {
+			e := echo.New()
+			e.Use(tracer.EchoMiddlewareFunc)
+			e.GET("/", echo.WrapHandler(http.HandlerFunc(panicking)))
+			return e
+		},
+	}
+
+	custom := func(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("custom: " + recovered.(string)))
+	}
+
+	for name, adapter := range adapters {
+		for _, test := range []struct {
+			handler RecoveryHandlerFunc
+			status  int
+			body    string
+		}{
+			{nil, http.StatusInternalServerError, "Internal Server Error"},
+			{custom, http.StatusServiceUnavailable, "custom: boom"},
+		} {
+			out.Reset()
+			w := httptest.NewRecorder()
+			adapter(newTracer(test.handler)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != test.status || !strings.Contains(w.Body.String(), test.body) {
+				t.Errorf("%s: unexpected response %d %q", name, w.Code, w.Body.String())
+			}
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("%s: expected the panic and the access log, got: %q", name, out.String())
+			}
+
+			var panicEntry, accessEntry map[string]interface{}
+			_ = json.Unmarshal([]byte(lines[0]), &panicEntry)
+			_ = json.Unmarshal([]byte(lines[1]), &accessEntry)
+
+			stack, _ := panicEntry["stack"].(string)
+			if panicEntry["level"] != "error" || panicEntry["msg"] != "panic recovered: boom" ||
+				!strings.Contains(stack, "recovery_test.go") || panicEntry["request_id"] == nil {
+				t.Errorf("%s: unexpected panic entry: %v", name, panicEntry)
+			}
+			if accessEntry["status"] != float64(test.status) {
+				t.Errorf("%s: unexpected access entry: %v", name, accessEntry)
+			}
+		}
+	}
+}
+
+func TestHttpTracer_RecoveryAbortHandler(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithRecovery(nil))
+	handler := tracer.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) })
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler, got %v", rec)
+		}
+		if strings.Contains(out.String(), "panic recovered") {
+			t.Errorf("unexpected panic log: %q", out.String())
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestHttpTracer_RecoveryFormat(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithFormat(FormatterJSON), WithRecovery(nil))
+	handler := tracer.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the panic and the access log records, got: %q", out.String())
+	}
+
+	var panicEntry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &panicEntry); err != nil {
+		t.Fatalf("invalid json %q: %v", lines[0], err)
+	}
+	stack, _ := panicEntry["stack"].(string)
+	if panicEntry["level"] != "error" || panicEntry["msg"] != "panic recovered: boom" || panicEntry["panic"] != "boom" ||
+		panicEntry["path"] != "/users" || panicEntry["request_id"] == nil || !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("unexpected panic entry: %v", panicEntry)
+	}
+}
+
+func TestHttpTracer_RecoveryAfterWrite(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithFormat(FormatterJSON), WithRecovery(nil))
+	handler := tracer.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("the written response must be kept, got %d %q", w.Code, w.Body.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var entry AccessLogEntry
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if entry.Status != http.StatusInternalServerError || !entry.Recovered || entry.Bytes != 7 {
+		t.Errorf("unexpected access entry: %+v", entry)
+	}
+}
+
+func TestHttpTracer_RecoveryAfterHijack(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithFormat(FormatterJSON),
+		WithRecovery(func(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+			t.Error("the RecoveryHandler must not be called on a hijacked connection")
+		}))
+	handler := tracer.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, _ = w.(http.Hijacker).Hijack()
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(hijackWriter{rec}, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() > 0 {
+		t.Errorf("no response must be written, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var entry AccessLogEntry
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("invalid json %q: %v", out.String(), err)
+	}
+	if entry.Status != http.StatusInternalServerError || !entry.Recovered || entry.State != StateHijacked {
+		t.Errorf("unexpected access entry: %+v", entry)
+	}
+}
@@ -0,0 +1,60 @@
+package ansilog
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// RequestIDGenerator returns a new request id.
+type RequestIDGenerator func() string
+
+// maxRequestIDLength is the maximum length of an incoming request id,
+// longer ids are replaced by a generated one.
+const maxRequestIDLength = 128
+
+// NewUUID returns a random (version 4) UUID, eg.: "9b2c3f0e-8d1a-4b5e-a6f7-0c1d2e3f4a5b".
+func NewUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// crockford is the ULID base32 alphabet.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a new ULID, a lexicographically sortable id
+// made of a 48 bit millisecond timestamp and 80 random bits,
+// eg.: "01ARZ3NDEKTSV4RRFFQ69G5FAV".
+func NewULID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()/int64(time.Millisecond))<<16)
+	_, _ = rand.Read(b[6:])
+
+	// 128 bits are encoded in 26 characters of 5 bits, the first one holds 3 bits only
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	id := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		id[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(id)
+}
+
+// validRequestID reports whether an incoming request id can be logged as is.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	return strings.IndexFunc(id, func(r rune) bool {
+		return r <= ' ' || r > '~' || r == '"'
+	}) < 0
+}
@@ -0,0 +1,286 @@
+package ansilog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/urfave/negroni"
+)
+
+// Connection states of the traced requests.
+const (
+	// StateHijacked is the state of the connections taken over
+	// by the handler through http.Hijacker, eg.: WebSockets.
+	StateHijacked = "hijacked"
+	// StateStreamed is the state of the responses flushed
+	// by the handler through http.Flusher, eg.: server-sent events.
+	StateStreamed = "streamed"
+	// StateNoResponse is the state of the requests whose handler
+	// returned without writing a response, eg.: after a panic.
+	StateNoResponse = "no response"
+)
+
+// responseWriter is a custom http.ResponseWriter that holds statusCode and contentLength,
+// status is zero until the response headers are written.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+	trace  *requestTrace
+}
+
+// newResponseWriter wraps w, the returned http.ResponseWriter implements
+// exactly the optional interfaces implemented by w
+// among http.Flusher, http.Hijacker, http.Pusher and io.ReaderFrom.
+func newResponseWriter(w http.ResponseWriter, rt *requestTrace) (*responseWriter, http.ResponseWriter) {
+	rw := &responseWriter{ResponseWriter: w, trace: rt}
+
+	var (
+		f = flusher{rw}
+		h = hijacker{rw}
+		p = pusher{rw}
+		r = readerFrom{rw}
+	)
+
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+	_, isPusher := w.(http.Pusher)
+	_, isReaderFrom := w.(io.ReaderFrom)
+
+	var mask int
+	for i, is := range []bool{isFlusher, isHijacker, isPusher, isReaderFrom} {
+		if is {
+			mask |= 1 << i
+		}
+	}
+
+	switch mask {
+	case 0:
+		return rw, rw
+	case 1:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+		}{rw, f}
+	case 2:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+		}{rw, h}
+	case 3:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{rw, f, h}
+	case 4:
+		return rw, struct {
+			*responseWriter
+			http.Pusher
+		}{rw, p}
+	case 5:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+		}{rw, f, p}
+	case 6:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+		}{rw, h, p}
+	case 7:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rw, f, h, p}
+	case 8:
+		return rw, struct {
+			*responseWriter
+			io.ReaderFrom
+		}{rw, r}
+	case 9:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{rw, f, r}
+	case 10:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, h, r}
+	case 11:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, f, h, r}
+	case 12:
+		return rw, struct {
+			*responseWriter
+			http.Pusher
+			io.ReaderFrom
+		}{rw, p, r}
+	case 13:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{rw, f, p, r}
+	case 14:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{rw, h, p, r}
+	default:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{rw, f, h, p, r}
+	}
+}
+
+// newNegroniResponseWriter wraps w, the returned http.ResponseWriter is
+// a negroni.ResponseWriter itself, so that the next negroni handlers
+// can assert it, and implements the optional interfaces implemented by w
+// among http.Hijacker and http.Pusher.
+func newNegroniResponseWriter(w negroni.ResponseWriter, rt *requestTrace) (*responseWriter, http.ResponseWriter) {
+	rw := &responseWriter{ResponseWriter: w, trace: rt}
+	nw := negroniResponseWriter{rw, flusher{rw}}
+
+	_, isHijacker := w.(http.Hijacker)
+	_, isPusher := w.(http.Pusher)
+
+	switch {
+	case isHijacker && isPusher:
+		return rw, struct {
+			negroniResponseWriter
+			http.Hijacker
+			http.Pusher
+		}{nw, hijacker{rw}, pusher{rw}}
+	case isHijacker:
+		return rw, struct {
+			negroniResponseWriter
+			http.Hijacker
+		}{nw, hijacker{rw}}
+	case isPusher:
+		return rw, struct {
+			negroniResponseWriter
+			http.Pusher
+		}{nw, pusher{rw}}
+	default:
+		return rw, nw
+	}
+}
+
+// WriteHeader records the first final status, superfluous calls are still
+// forwarded so that the server can report them.
+// Informational (1xx) headers are forwarded but not recorded,
+// except for 101 Switching Protocols.
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.status == 0 && !informational(status) {
+		rw.trace.markFirstByte()
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// informational reports whether status is a 1xx status followed by the final one.
+func informational(status int) bool {
+	return status >= 100 && status < 200 && status != http.StatusSwitchingProtocols
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.ResponseWriter.Header()
+}
+
+func (rw *responseWriter) Write(bytes []byte) (int, error) {
+	rw.trace.markFirstByte()
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	size, err := rw.ResponseWriter.Write(bytes)
+	rw.size += size
+	return size, err
+}
+
+func (rw *responseWriter) Status() int {
+	return rw.status
+}
+
+func (rw *responseWriter) Size() int {
+	return rw.size
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, used by http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+type flusher struct{ *responseWriter }
+
+func (w flusher) Flush() {
+	w.trace.markFirstByte()
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.trace.state = StateStreamed
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// negroniResponseWriter forwards the negroni.ResponseWriter methods
+// to the wrapped negroni.ResponseWriter.
+type negroniResponseWriter struct {
+	*responseWriter
+	http.Flusher
+}
+
+func (w negroniResponseWriter) Written() bool {
+	return w.ResponseWriter.(negroni.ResponseWriter).Written()
+}
+
+func (w negroniResponseWriter) Before(before func(negroni.ResponseWriter)) {
+	w.ResponseWriter.(negroni.ResponseWriter).Before(before)
+}
+
+type hijacker struct{ *responseWriter }
+
+func (w hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		w.trace.state = StateHijacked
+	}
+	return conn, buf, err
+}
+
+type pusher struct{ *responseWriter }
+
+func (w pusher) Push(target string, opts *http.PushOptions) error {
+	return w.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+type readerFrom struct{ *responseWriter }
+
+func (w readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	w.trace.markFirstByte()
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	w.size += int(n)
+	return n, err
+}
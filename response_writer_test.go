@@ -0,0 +1,193 @@
+package ansilog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/urfave/negroni"
+)
+
+type flushWriter struct{ http.ResponseWriter }
+
+func (flushWriter) Flush() {}
+
+type hijackWriter struct{ http.ResponseWriter }
+
+func (hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return nil, nil, nil }
+
+type pushWriter struct{ http.ResponseWriter }
+
+func (pushWriter) Push(string, *http.PushOptions) error { return nil }
+
+type readFromWriter struct{ http.ResponseWriter }
+
+func (readFromWriter) ReadFrom(io.Reader) (int64, error) { return 0, nil }
+
+type fullWriter struct{ http.ResponseWriter }
+
+func (fullWriter) Flush()                                       {}
+func (fullWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return nil, nil, nil }
+func (fullWriter) Push(string, *http.PushOptions) error         { return nil }
+func (fullWriter) ReadFrom(io.Reader) (int64, error)            { return 0, nil }
+
+func TestNewResponseWriter_Interfaces(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	tests := []struct {
+		name string
+		w    http.ResponseWriter
+	}{
+		{"none", struct{ http.ResponseWriter }{rec}},
+		{"recorder", rec},
+		{"flusher", flushWriter{rec}},
+		{"hijacker", hijackWriter{rec}},
+		{"pusher", pushWriter{rec}},
+		{"readerFrom", readFromWriter{rec}},
+		{"all", fullWriter{rec}},
+		{"negroni", negroni.NewResponseWriter(rec)},
+		{"negroni flusher", negroni.NewResponseWriter(flushWriter{rec})},
+	}
+
+	tracer := NewHttpTracerWithOptions(WithWriter(ioutil.Discard))
+
+	for _, test := range tests {
+		_, wrapped := newResponseWriter(test.w, &requestTrace{})
+
+		// the negroni adapter must pass the same interfaces
+		var passed http.ResponseWriter
+		tracer.ServeHTTP(test.w, httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {
+			passed = w
+		})
+
+		for _, check := range []struct {
+			name string
+			is   func(w interface{}) bool
+		}{
+			{"http.Flusher", func(w interface{}) bool { _, ok := w.(http.Flusher); return ok }},
+			{"http.Hijacker", func(w interface{}) bool { _, ok := w.(http.Hijacker); return ok }},
+			{"http.Pusher", func(w interface{}) bool { _, ok := w.(http.Pusher); return ok }},
+			{"io.ReaderFrom", func(w interface{}) bool { _, ok := w.(io.ReaderFrom); return ok }},
+		} {
+			if want, got := check.is(test.w), check.is(wrapped); got != want {
+				t.Errorf("%s: %s got %v, want %v", test.name, check.name, got, want)
+			}
+			if want, got := check.is(test.w), check.is(passed); got != want {
+				t.Errorf("%s, negroni adapter: %s got %v, want %v", test.name, check.name, got, want)
+			}
+		}
+
+		if _, ok := wrapped.(interface{ Status() int }); !ok {
+			t.Errorf("%s: missing Status()", test.name)
+		}
+	}
+}
+
+func TestHttpTracer_HijackedAndStreamed(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithColorsMode(ConsoleColorsModeDisabled),
+		WithTemplateText("{{.Status}} {{.Entry.State}} {{.Entry.Bytes}}"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		_ = buf.Flush()
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("data: ping\n\n"))
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.(http.Flusher).Flush()
+	})
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, strings.NewReader("content"))
+	})
+
+	server := httptest.NewServer(tracer.Handler(mux))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\n\r\n"))
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	_ = conn.Close()
+	if !strings.HasPrefix(line, "HTTP/1.1 101") {
+		t.Fatalf("unexpected response: %q", line)
+	}
+
+	for _, path := range []string{"/events", "/missing", "/file"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	want := "hijacked hijacked 0\n200 streamed streamed 36\n404 streamed streamed 0\n200  7\n"
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHttpTracer_NegroniChain(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracer := NewHttpTracerWithOptions(WithWriter(out), WithColorsMode(ConsoleColorsModeDisabled),
+		WithTemplateText("{{.Status}} {{.Entry.Bytes}}"))
+
+	logged := &bytes.Buffer{}
+	logger := negroni.NewLogger()
+	logger.ALogger = log.New(logged, "", 0)
+	logger.SetFormat("{{.Status}}")
+
+	var before bool
+	n := negroni.New()
+	n.Use(tracer)
+	n.Use(logger)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nw, ok := w.(negroni.ResponseWriter)
+		if !ok {
+			t.Fatal("the tracer did not pass a negroni.ResponseWriter")
+		}
+		nw.Before(func(negroni.ResponseWriter) { before = true })
+		if nw.Written() {
+			t.Error("Written() is true before writing")
+		}
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("tea"))
+		if !nw.Written() {
+			t.Error("Written() is false after writing")
+		}
+	})
+
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !before {
+		t.Error("the Before func was not called")
+	}
+	if want, got := "418 3\n", out.String(); got != want {
+		t.Errorf("tracer got %q, want %q", got, want)
+	}
+	if want, got := "418\n", logged.String(); got != want {
+		t.Errorf("negroni logger got %q, want %q", got, want)
+	}
+}
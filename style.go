@@ -0,0 +1,106 @@
+package ansilog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Attribute is an SGR text attribute.
+type Attribute int
+
+const (
+	AttrBold          Attribute = 1
+	AttrFaint         Attribute = 2
+	AttrItalic        Attribute = 3
+	AttrUnderline     Attribute = 4
+	AttrBlink         Attribute = 5
+	AttrReverse       Attribute = 7
+	AttrConceal       Attribute = 8
+	AttrStrikethrough Attribute = 9
+)
+
+// Style combines a foreground color, a background color
+// and any number of attributes.
+// Styles are immutable, every method returns a new Style,
+// so that a base style can be safely shared and extended.
+type Style struct {
+	fg    Color
+	bg    Color
+	attrs []Attribute
+}
+
+// NewStyle returns an empty Style.
+func NewStyle() Style {
+	return Style{}
+}
+
+// Foreground returns a copy of the style with the given foreground color.
+func (s Style) Foreground(c Color) Style {
+	s.fg = c
+	return s
+}
+
+// Background returns a copy of the style with the given background color.
+func (s Style) Background(c Color) Style {
+	s.bg = c
+	return s
+}
+
+// Add returns a copy of the style with the given attributes.
+func (s Style) Add(attrs ...Attribute) Style {
+	s.attrs = append(append([]Attribute{}, s.attrs...), attrs...)
+	return s
+}
+
+// Bold returns a copy of the style with the bold attribute.
+func (s Style) Bold() Style { return s.Add(AttrBold) }
+
+// Faint returns a copy of the style with the faint (dim) attribute.
+func (s Style) Faint() Style { return s.Add(AttrFaint) }
+
+// Italic returns a copy of the style with the italic attribute.
+func (s Style) Italic() Style { return s.Add(AttrItalic) }
+
+// Underline returns a copy of the style with the underline attribute.
+func (s Style) Underline() Style { return s.Add(AttrUnderline) }
+
+// Blink returns a copy of the style with the blink attribute.
+func (s Style) Blink() Style { return s.Add(AttrBlink) }
+
+// Reverse returns a copy of the style with the reverse attribute.
+func (s Style) Reverse() Style { return s.Add(AttrReverse) }
+
+// Conceal returns a copy of the style with the conceal (hidden) attribute.
+func (s Style) Conceal() Style { return s.Add(AttrConceal) }
+
+// Strikethrough returns a copy of the style with the strikethrough attribute.
+func (s Style) Strikethrough() Style { return s.Add(AttrStrikethrough) }
+
+// Color returns the combined SGR code of the style.
+func (s Style) Color() Color {
+	codes := make([]string, 0, len(s.attrs)+2)
+	for _, attr := range s.attrs {
+		codes = append(codes, strconv.Itoa(int(attr)))
+	}
+	if len(s.fg) > 0 {
+		codes = append(codes, strings.TrimSuffix(string(s.fg), "m"))
+	}
+	if len(s.bg) > 0 {
+		codes = append(codes, strings.TrimSuffix(string(s.bg), "m"))
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	return Color(strings.Join(codes, ";") + "m")
+}
+
+// Paint return the argument as a style escaped string.
+func (s Style) Paint(arg interface{}) string {
+	return colored(fmt.Sprint(arg), s.Color())
+}
+
+// Painter returns a Painter that can be stored and reused.
+func (s Style) Painter() Painter {
+	return NewPainter(s.Color())
+}
@@ -0,0 +1,417 @@
+package ansilog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Alignment is the horizontal alignment of a table column.
+type Alignment int
+
+const (
+	AlignLeft Alignment = iota
+	AlignRight
+	AlignCenter
+)
+
+// BorderStyle is the style of the table borders.
+type BorderStyle int
+
+const (
+	// BorderBox draws the borders with box-drawing characters.
+	BorderBox BorderStyle = iota
+	// BorderASCII draws the borders with +, - and | characters.
+	BorderASCII
+	// BorderMarkdown renders a GitHub flavored markdown table.
+	BorderMarkdown
+	// BorderNone separates the columns with two spaces.
+	BorderNone
+)
+
+// Column is the configuration of a table column.
+type Column struct {
+	Header string
+	Align  Alignment
+
+	// Painter is applied to every cell of the column.
+	Painter Painter
+
+	// MaxWidth is the maximum visible width of the column,
+	// 0 means no limit.
+	MaxWidth int
+
+	// Wrap wraps the cells exceeding the column width
+	// instead of truncating them.
+	Wrap bool
+}
+
+// Table renders rows of cells in auto-sized columns,
+// escape codes are ignored when computing the columns width.
+type Table struct {
+	Columns []Column
+	Rows    [][]string
+
+	Border        BorderStyle
+	BorderPainter Painter
+	HeaderPainter Painter
+
+	// MaxWidth is the maximum visible width of the whole table,
+	// the widest columns are shrunk to fit, 0 means no limit.
+	MaxWidth int
+
+	// Ellipsis is appended to the truncated cells.
+	// Default value is "…".
+	Ellipsis string
+
+	// ColorsMode determine if painters must be used or not,
+	// it is resolved against the writer passed to Render.
+	ColorsMode ConsoleColorsModeEnum
+}
+
+// NewTable returns a new Table with the given headers.
+func NewTable(headers ...string) *Table {
+	t := &Table{
+		Border:        BorderBox,
+		HeaderPainter: NewStyle().Bold().Painter(),
+		Ellipsis:      "…",
+	}
+	for _, header := range headers {
+		t.Columns = append(t.Columns, Column{Header: header})
+	}
+	return t
+}
+
+// AddRow append a row, cells are formatted with fmt.Sprint.
+func (t *Table) AddRow(cells ...interface{}) *Table {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		row[i] = fmt.Sprint(cell)
+	}
+	t.Rows = append(t.Rows, row)
+	return t
+}
+
+// String returns the rendered table, colors are used
+// only if ColorsMode is ConsoleColorsModeEnabled.
+func (t *Table) String() string {
+	buf := &bytes.Buffer{}
+	_ = t.Render(buf)
+	return buf.String()
+}
+
+// Render writes the table to w.
+func (t *Table) Render(w io.Writer) error {
+	colors := t.ColorsMode.Enabled(w)
+
+	columns := t.columns()
+	if len(columns) == 0 {
+		return nil
+	}
+
+	hasHeader := false
+	for _, column := range columns {
+		if len(column.Header) > 0 {
+			hasHeader = true
+			break
+		}
+	}
+
+	widths := t.widths(columns, hasHeader)
+	b := &strings.Builder{}
+	border := t.borderChars()
+
+	t.writeSeparator(b, border.top, widths, columns, colors)
+
+	if hasHeader {
+		headers := make([]string, len(columns))
+		for i, column := range columns {
+			headers[i] = column.Header
+		}
+		t.writeRow(b, border, headers, widths, columns, t.HeaderPainter, colors)
+		t.writeSeparator(b, border.middle, widths, columns, colors)
+	}
+
+	for _, row := range t.Rows {
+		t.writeRow(b, border, row, widths, columns, nil, colors)
+	}
+
+	t.writeSeparator(b, border.bottom, widths, columns, colors)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// columns returns the configured columns plus the missing ones.
+func (t *Table) columns() []Column {
+	columns := append([]Column{}, t.Columns...)
+	for _, row := range t.Rows {
+		for len(columns) < len(row) {
+			columns = append(columns, Column{})
+		}
+	}
+	return columns
+}
+
+// widths computes the width of every column.
+func (t *Table) widths(columns []Column, hasHeader bool) []int {
+	widths := make([]int, len(columns))
+
+	measure := func(i int, cell string) {
+		for _, line := range restyle(strings.Split(cell, "\n")) {
+			if w := VisibleWidth(line); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	if hasHeader {
+		for i, column := range columns {
+			measure(i, column.Header)
+		}
+	}
+	for _, row := range t.Rows {
+		for i, cell := range row {
+			measure(i, cell)
+		}
+	}
+
+	for i, column := range columns {
+		if column.MaxWidth > 0 && widths[i] > column.MaxWidth {
+			widths[i] = column.MaxWidth
+		}
+	}
+
+	if t.MaxWidth > 0 {
+		overhead := 3*len(columns) + 1
+		if t.Border == BorderNone {
+			overhead = 2 * (len(columns) - 1)
+		}
+
+		for {
+			total, widest := overhead, 0
+			for i, w := range widths {
+				total += w
+				if w > widths[widest] {
+					widest = i
+				}
+			}
+			if total <= t.MaxWidth || widths[widest] <= 1 {
+				break
+			}
+			widths[widest]--
+		}
+	}
+
+	return widths
+}
+
+// writeRow writes a row, cells can span multiple lines.
+func (t *Table) writeRow(b *strings.Builder, border borderChars, row []string, widths []int,
+	columns []Column, painter Painter, colors bool) {
+
+	cells := make([][]string, len(columns))
+	height := 1
+	for i := range columns {
+		cell := ""
+		if i < len(row) {
+			cell = row[i]
+		}
+		if !colors {
+			cell = Strip(cell)
+		}
+
+		for _, line := range restyle(strings.Split(cell, "\n")) {
+			if VisibleWidth(line) <= widths[i] {
+				cells[i] = append(cells[i], line)
+			} else if columns[i].Wrap {
+				cells[i] = append(cells[i], wrap(line, widths[i])...)
+			} else {
+				cells[i] = append(cells[i], Truncate(line, widths[i], t.Ellipsis))
+			}
+		}
+
+		if len(cells[i]) > height {
+			height = len(cells[i])
+		}
+	}
+
+	for l := 0; l < height; l++ {
+		line := &strings.Builder{}
+		line.WriteString(t.paintBorder(border.left, colors))
+		for i, column := range columns {
+			if i > 0 {
+				line.WriteString(t.paintBorder(border.separator, colors))
+			}
+
+			text := ""
+			if l < len(cells[i]) {
+				text = cells[i][l]
+			}
+
+			if colors && len(text) > 0 {
+				if painter != nil {
+					text = painter(text)
+				} else if column.Painter != nil {
+					text = column.Painter(text)
+				}
+			}
+
+			switch column.Align {
+			case AlignRight:
+				text = PadLeft(text, widths[i])
+			case AlignCenter:
+				text = Center(text, widths[i])
+			default:
+				text = PadRight(text, widths[i])
+			}
+			line.WriteString(text)
+		}
+		line.WriteString(t.paintBorder(border.right, colors))
+
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteByte('\n')
+	}
+}
+
+// writeSeparator writes an horizontal border line.
+func (t *Table) writeSeparator(b *strings.Builder, chars *separatorChars, widths []int, columns []Column, colors bool) {
+	if chars == nil {
+		return
+	}
+
+	line := &strings.Builder{}
+	line.WriteString(chars.left)
+	for i, w := range widths {
+		if i > 0 {
+			line.WriteString(chars.cross)
+		}
+
+		fill := strings.Repeat(chars.fill, w+2)
+		if t.Border == BorderMarkdown {
+			switch columns[i].Align {
+			case AlignRight:
+				fill = strings.Repeat(chars.fill, w+1) + ":"
+			case AlignCenter:
+				fill = ":" + strings.Repeat(chars.fill, w) + ":"
+			}
+		}
+		line.WriteString(fill)
+	}
+	line.WriteString(chars.right)
+	line.WriteByte('\n')
+
+	b.WriteString(t.paintBorder(line.String(), colors))
+}
+
+func (t *Table) paintBorder(s string, colors bool) string {
+	if colors && t.BorderPainter != nil && len(strings.TrimSpace(s)) > 0 {
+		return t.BorderPainter(s)
+	}
+	return s
+}
+
+type separatorChars struct {
+	left, fill, cross, right string
+}
+
+type borderChars struct {
+	left, separator, right string
+	top, middle, bottom    *separatorChars
+}
+
+func (t *Table) borderChars() borderChars {
+	switch t.Border {
+	case BorderASCII:
+		sep := &separatorChars{"+", "-", "+", "+"}
+		return borderChars{"| ", " | ", " |", sep, sep, sep}
+	case BorderMarkdown:
+		return borderChars{"| ", " | ", " |", nil, &separatorChars{"|", "-", "|", "|"}, nil}
+	case BorderNone:
+		return borderChars{"", "  ", "", nil, nil, nil}
+	default:
+		return borderChars{"│ ", " │ ", " │",
+			&separatorChars{"┌", "─", "┬", "┐"},
+			&separatorChars{"├", "─", "┼", "┤"},
+			&separatorChars{"└", "─", "┴", "┘"}}
+	}
+}
+
+// wrap splits s in lines of the given visible width, breaking at spaces
+// when possible, escape sequences are preserved.
+func wrap(s string, width int) (lines []string) {
+	current, currentWidth := "", 0
+	for _, word := range strings.Split(s, " ") {
+		wordWidth := VisibleWidth(word)
+
+		if currentWidth > 0 && currentWidth+1+wordWidth > width {
+			lines = append(lines, current)
+			current, currentWidth = "", 0
+		}
+		if currentWidth > 0 {
+			current += " "
+			currentWidth++
+		}
+
+		for wordWidth > width-currentWidth {
+			head, tail := cut(word, width-currentWidth)
+			lines = append(lines, current+head)
+			current, currentWidth = "", 0
+			word, wordWidth = tail, VisibleWidth(tail)
+		}
+		current += word
+		currentWidth += wordWidth
+	}
+	return restyle(append(lines, current))
+}
+
+// restyle makes every line self-contained: the styles still active
+// at the end of a line are reset and re-opened at the beginning of the next one,
+// so that they don't leak across the table borders.
+func restyle(lines []string) []string {
+	active := ""
+	for l, line := range lines {
+		styled := active + line
+		for i := 0; i < len(line); {
+			n := escapeLen(line[i:])
+			if n == 0 {
+				_, size := utf8.DecodeRuneInString(line[i:])
+				i += size
+				continue
+			}
+			switch seq := line[i : i+n]; {
+			case seq == clear || seq == esc+"m":
+				active = ""
+			case strings.HasSuffix(seq, "m") && strings.HasPrefix(seq, esc):
+				active += seq
+			}
+			i += n
+		}
+		if len(active) > 0 {
+			styled += clear
+		}
+		lines[l] = styled
+	}
+	return lines
+}
+
+// cut splits s at the given visible width,
+// escape sequences are kept in the head.
+func cut(s string, width int) (head, tail string) {
+	current := 0
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		size, glyphWidth := glyphLen(s[i:])
+		if current+glyphWidth > width && current > 0 {
+			return s[:i], s[i:]
+		}
+		current += glyphWidth
+		i += size
+	}
+	return s, ""
+}
@@ -0,0 +1,109 @@
+package ansilog
+
+import (
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	table := NewTable("Name", "Status", "Latency")
+	table.ColorsMode = ConsoleColorsModeDisabled
+	table.Columns[2].Align = AlignRight
+	table.AddRow("postgres", Green("OK"), "2ms")
+	table.AddRow("日本", Red("FAIL"), "1.5s")
+
+	want := "" +
+		"┌──────────┬────────┬─────────┐\n" +
+		"│ Name     │ Status │ Latency │\n" +
+		"├──────────┼────────┼─────────┤\n" +
+		"│ postgres │ OK     │     2ms │\n" +
+		"│ 日本     │ FAIL   │    1.5s │\n" +
+		"└──────────┴────────┴─────────┘\n"
+	if got := table.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	table.ColorsMode = ConsoleColorsModeEnabled
+	want = "" +
+		"┌──────────┬────────┬─────────┐\n" +
+		"│ \033[1mName\033[0m     │ \033[1mStatus\033[0m │ \033[1mLatency\033[0m │\n" +
+		"├──────────┼────────┼─────────┤\n" +
+		"│ postgres │ \033[32mOK\033[0m     │     2ms │\n" +
+		"│ 日本     │ \033[31mFAIL\033[0m   │    1.5s │\n" +
+		"└──────────┴────────┴─────────┘\n"
+	if got := table.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTable_Markdown(t *testing.T) {
+	table := NewTable("Key", "Value")
+	table.ColorsMode = ConsoleColorsModeDisabled
+	table.Border = BorderMarkdown
+	table.Columns[1].Align = AlignCenter
+	table.AddRow("a", "b")
+
+	want := "" +
+		"| Key | Value |\n" +
+		"|-----|:-----:|\n" +
+		"| a   |   b   |\n"
+	if got := table.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTable_MaxWidth(t *testing.T) {
+	table := NewTable()
+	table.Border = BorderNone
+	table.MaxWidth = 12
+	table.Columns = []Column{{}, {Wrap: true}}
+	table.AddRow("key", "a long value")
+	table.AddRow("longer key", "v")
+
+	want := "" +
+		"key    a\n" +
+		"       long\n" +
+		"       value\n" +
+		"long…  v\n"
+	if got := table.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTable_WrapColors(t *testing.T) {
+	table := NewTable()
+	table.ColorsMode = ConsoleColorsModeEnabled
+	table.Columns = []Column{{MaxWidth: 5, Wrap: true}, {}}
+	table.AddRow(Red("hello world foo"), "x")
+
+	want := "" +
+		"┌───────┬───┐\n" +
+		"│ \033[31mhello\033[0m │ x │\n" +
+		"│ \033[31mworld\033[0m │   │\n" +
+		"│ \033[31mfoo\033[0m   │   │\n" +
+		"└───────┴───┘\n"
+	if got := table.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTable_MultilineColors(t *testing.T) {
+	table := NewTable()
+	table.ColorsMode = ConsoleColorsModeEnabled
+	table.AddRow(Red("x\ny"), "z")
+
+	want := "" +
+		"┌───┬───┐\n" +
+		"│ \033[31mx\033[0m │ z │\n" +
+		"│ \033[31my\033[0m │   │\n" +
+		"└───┴───┘\n"
+	if got := table.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCut(t *testing.T) {
+	head, tail := cut("👩‍💻👩‍💻👩‍💻", 5)
+	if head != "👩‍💻👩‍💻" || tail != "👩‍💻" {
+		t.Errorf("got %q %q", head, tail)
+	}
+}
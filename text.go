@@ -0,0 +1,209 @@
+package ansilog
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Strip returns s without ANSI escape sequences.
+func Strip(s string) string {
+	if !strings.Contains(s, "\033") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// VisibleWidth returns the number of terminal cells needed to print s,
+// escape sequences are ignored and East Asian wide characters and emoji
+// count as two cells.
+func VisibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		size, glyphWidth := glyphLen(s[i:])
+		width += glyphWidth
+		i += size
+	}
+	return width
+}
+
+// Truncate cuts s to the given visible width, appending ellipsis
+// when the text is truncated, eg.: Truncate(Red("hello world"), 8, "...").
+// Escape sequences are preserved and a reset is appended
+// if the truncated text was styled.
+// A zero or negative width returns an empty string.
+func Truncate(s string, width int, ellipsis string) string {
+	if width <= 0 {
+		return ""
+	}
+	if VisibleWidth(s) <= width {
+		return s
+	}
+
+	limit := width - VisibleWidth(ellipsis)
+	if limit < 0 {
+		return Truncate(ellipsis, width, "")
+	}
+
+	var b strings.Builder
+	styled := false
+	current := 0
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			styled = true
+			i += n
+			continue
+		}
+		size, glyphWidth := glyphLen(s[i:])
+		if current+glyphWidth > limit {
+			break
+		}
+		b.WriteString(s[i : i+size])
+		current += glyphWidth
+		i += size
+	}
+
+	b.WriteString(ellipsis)
+	if styled {
+		b.WriteString(clear)
+	}
+	return b.String()
+}
+
+// PadRight left-aligns s in a column of the given visible width.
+func PadRight(s string, width int) string {
+	if pad := width - VisibleWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+// PadLeft right-aligns s in a column of the given visible width.
+func PadLeft(s string, width int) string {
+	if pad := width - VisibleWidth(s); pad > 0 {
+		return strings.Repeat(" ", pad) + s
+	}
+	return s
+}
+
+// Center centers s in a column of the given visible width,
+// the extra space goes to the right.
+func Center(s string, width int) string {
+	if pad := width - VisibleWidth(s); pad > 0 {
+		return strings.Repeat(" ", pad/2) + s + strings.Repeat(" ", pad-pad/2)
+	}
+	return s
+}
+
+// escapeLen returns the length of the escape sequence at the beginning of s,
+// or 0 if s does not start with an escape sequence.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\033' {
+		return 0
+	}
+
+	switch s[1] {
+	case '[': // CSI: parameters, intermediates and a final byte
+		i := 2
+		for i < len(s) && s[i] >= 0x30 && s[i] <= 0x3F {
+			i++
+		}
+		for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2F {
+			i++
+		}
+		if i < len(s) && s[i] >= 0x40 && s[i] <= 0x7E {
+			return i + 1
+		}
+		return i
+	case ']': // OSC: terminated by BEL or ST
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\033' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	default:
+		return 2
+	}
+}
+
+const zeroWidthJoiner = '\u200D'
+
+// glyphLen returns the length in bytes and the visible width of the glyph
+// at the beginning of s, the runes joined by a zero width joiner
+// are part of the same glyph, eg.: 👩‍💻 is two cells wide.
+func glyphLen(s string) (size, width int) {
+	r, size := utf8.DecodeRuneInString(s)
+	width = RuneWidth(r)
+	joined := r == zeroWidthJoiner
+	for size < len(s) {
+		next, n := utf8.DecodeRuneInString(s[size:])
+		if !joined && next != zeroWidthJoiner {
+			break
+		}
+		size += n
+		joined = !joined
+	}
+	return size, width
+}
+
+// RuneWidth returns the number of terminal cells needed to print r.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7F && r < 0xA0):
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case isWide(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// wideRanges are the East Asian Wide and Fullwidth ranges, emoji included.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC},
+	{0x23F0, 0x23F0}, {0x23F3, 0x23F3}, {0x25FD, 0x25FE}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5},
+	{0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0}, {0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF},
+	{0xA960, 0xA97F}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x16FE0, 0x16FE4},
+	{0x17000, 0x18AFF}, {0x1B000, 0x1B2FF}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F200, 0x1F251}, {0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF}, {0x1F7E0, 0x1F7EB}, {0x1F90C, 0x1F9FF}, {0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+func isWide(r rune) bool {
+	i := sort.Search(len(wideRanges), func(i int) bool {
+		return wideRanges[i][1] >= r
+	})
+	return i < len(wideRanges) && r >= wideRanges[i][0]
+}
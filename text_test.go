@@ -0,0 +1,70 @@
+package ansilog
+
+import "testing"
+
+func TestVisibleWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"hello", 5},
+		{Red("hello"), 5},
+		{NewStyle().Foreground(ColorBlue).Bold().Paint(Green("ab") + "c"), 3},
+		{"日本語", 6},
+		{"é", 1},
+		{"👍", 2},
+		{"👩‍💻", 2},
+		{"\033]8;;http://example.com\033\\link\033]8;;\033\\", 4},
+	}
+
+	for _, tt := range tests {
+		if got := VisibleWidth(tt.s); got != tt.want {
+			t.Errorf("%q: got %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestStrip(t *testing.T) {
+	if got := Strip(Red("a") + BgBlue("b") + "c"); got != "abc" {
+		t.Errorf("got %q, want %q", got, "abc")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s        string
+		width    int
+		ellipsis string
+		want     string
+	}{
+		{"hello", 10, "...", "hello"},
+		{"hello world", 8, "...", "hello..."},
+		{Red("hello world"), 8, "...", "\033[31mhello...\033[0m"},
+		{"日本語", 5, "…", "日本…"},
+		{"hello", 2, "...", ".."},
+		{"👩‍💻👩‍💻👩‍💻", 4, "", "👩‍💻👩‍💻"},
+		{"👩‍💻👩‍💻👩‍💻", 3, "", "👩‍💻"},
+		{"hello", 0, "...", ""},
+		{"hello", -1, "...", ""},
+		{"", -1, "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.width, tt.ellipsis); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	s := Red("ab")
+	if got := PadRight(s, 5); got != s+"   " {
+		t.Errorf("PadRight: got %q", got)
+	}
+	if got := PadLeft(s, 5); got != "   "+s {
+		t.Errorf("PadLeft: got %q", got)
+	}
+	if got := Center(s, 5); got != " "+s+"  " {
+		t.Errorf("Center: got %q", got)
+	}
+}
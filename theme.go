@@ -0,0 +1,261 @@
+package ansilog
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/oblq/swap"
+	"github.com/sirupsen/logrus"
+)
+
+// Theme is the set of colors used by Logger, HttpTracer and KVLogger.
+// Every color can be set by name from a YAML file, see ParseColor, eg.:
+//
+//	name: custom
+//	info: light-blue
+//	error: bold red
+//	key: "#268bd2"
+//	methods:
+//	  GET: green
+//	  DELETE: bold red
+type Theme struct {
+	Name string `yaml:"name"`
+
+	// Logger levels
+	Trace Color `yaml:"trace"`
+	Debug Color `yaml:"debug"`
+	Info  Color `yaml:"info"`
+	Warn  Color `yaml:"warn"`
+	Error Color `yaml:"error"`
+	Fatal Color `yaml:"fatal"`
+	Panic Color `yaml:"panic"`
+
+	// Key and Value are used for Logger fields and KVLogger pairs.
+	Key   Color `yaml:"key"`
+	Value Color `yaml:"value"`
+
+	// Muted is used for secondary text such as timestamps.
+	Muted Color `yaml:"muted"`
+
+	// Punctuation is used for separators and brackets.
+	Punctuation Color `yaml:"punctuation"`
+
+	// HttpTracer
+	Host      Color            `yaml:"host"`
+	Methods   map[string]Color `yaml:"methods"`
+	Status2xx Color            `yaml:"status_2xx"`
+	Status3xx Color            `yaml:"status_3xx"`
+	Status4xx Color            `yaml:"status_4xx"`
+	Status5xx Color            `yaml:"status_5xx"`
+}
+
+// ThemeDark is the default theme, for dark terminal backgrounds.
+var ThemeDark = &Theme{
+	Name:        "dark",
+	Trace:       ColorLightGrey,
+	Debug:       ColorLightGrey,
+	Info:        ColorCyan,
+	Warn:        ColorYellow,
+	Error:       ColorRed,
+	Fatal:       ColorRed,
+	Panic:       ColorRed,
+	Key:         ColorBlue,
+	Muted:       ColorDarkGrey,
+	Punctuation: ColorBlue,
+	Host:        ColorYellow,
+	Methods: map[string]Color{
+		http.MethodGet:     ColorGreen,
+		http.MethodPost:    ColorBlue,
+		http.MethodPut:     ColorCyan,
+		http.MethodDelete:  ColorRed,
+		http.MethodPatch:   ColorYellow,
+		http.MethodHead:    ColorMagenta,
+		http.MethodOptions: ColorWhite,
+	},
+	Status2xx: ColorGreen,
+	Status3xx: ColorCyan,
+	Status4xx: ColorMagenta,
+	Status5xx: ColorRed,
+}
+
+// ThemeLight is for light terminal backgrounds.
+var ThemeLight = &Theme{
+	Name:        "light",
+	Trace:       ColorDarkGrey,
+	Debug:       ColorDarkGrey,
+	Info:        ColorBlue,
+	Warn:        Palette(130),
+	Error:       ColorRed,
+	Fatal:       "1;31m",
+	Panic:       "1;31m",
+	Key:         ColorBlue,
+	Value:       ColorBlack,
+	Muted:       ColorDarkGrey,
+	Punctuation: ColorDarkGrey,
+	Host:        ColorMagenta,
+	Methods: map[string]Color{
+		http.MethodGet:     ColorGreen,
+		http.MethodPost:    ColorBlue,
+		http.MethodPut:     ColorCyan,
+		http.MethodDelete:  ColorRed,
+		http.MethodPatch:   ColorMagenta,
+		http.MethodHead:    ColorDarkGrey,
+		http.MethodOptions: ColorBlack,
+	},
+	Status2xx: ColorGreen,
+	Status3xx: ColorCyan,
+	Status4xx: ColorMagenta,
+	Status5xx: ColorRed,
+}
+
+// ThemeSolarized uses the solarized palette,
+// downgraded to the nearest supported colors if needed.
+var ThemeSolarized = &Theme{
+	Name:        "solarized",
+	Trace:       Hex("#586e75"),
+	Debug:       Hex("#586e75"),
+	Info:        Hex("#268bd2"),
+	Warn:        Hex("#b58900"),
+	Error:       Hex("#dc322f"),
+	Fatal:       Hex("#d33682"),
+	Panic:       Hex("#d33682"),
+	Key:         Hex("#2aa198"),
+	Value:       Hex("#839496"),
+	Muted:       Hex("#586e75"),
+	Punctuation: Hex("#6c71c4"),
+	Host:        Hex("#b58900"),
+	Methods: map[string]Color{
+		http.MethodGet:     Hex("#859900"),
+		http.MethodPost:    Hex("#268bd2"),
+		http.MethodPut:     Hex("#2aa198"),
+		http.MethodDelete:  Hex("#dc322f"),
+		http.MethodPatch:   Hex("#cb4b16"),
+		http.MethodHead:    Hex("#6c71c4"),
+		http.MethodOptions: Hex("#93a1a1"),
+	},
+	Status2xx: Hex("#859900"),
+	Status3xx: Hex("#2aa198"),
+	Status4xx: Hex("#cb4b16"),
+	Status5xx: Hex("#dc322f"),
+}
+
+// ThemeMonochrome uses text attributes only.
+var ThemeMonochrome = &Theme{
+	Name:      "monochrome",
+	Trace:     "2m",
+	Debug:     "2m",
+	Warn:      "1m",
+	Error:     "1m",
+	Fatal:     "1;7m",
+	Panic:     "1;7m",
+	Key:       "1m",
+	Muted:     "2m",
+	Host:      "4m",
+	Status4xx: "1m",
+	Status5xx: "1;7m",
+}
+
+// DefaultTheme is the theme used when none is configured.
+var DefaultTheme = ThemeDark
+
+var (
+	themesMutex sync.RWMutex
+	themes      = map[string]*Theme{
+		ThemeDark.Name:       ThemeDark,
+		ThemeLight.Name:      ThemeLight,
+		ThemeSolarized.Name:  ThemeSolarized,
+		ThemeMonochrome.Name: ThemeMonochrome,
+	}
+)
+
+// RegisterTheme makes a theme available by name,
+// so that it can be selected from config files.
+func RegisterTheme(theme *Theme) {
+	if theme == nil || len(theme.Name) == 0 {
+		panic("ansilog: RegisterTheme theme must have a name")
+	}
+
+	themesMutex.Lock()
+	defer themesMutex.Unlock()
+	themes[strings.ToLower(theme.Name)] = theme
+}
+
+// ThemeByName returns a built-in or registered theme.
+func ThemeByName(name string) (theme *Theme, ok bool) {
+	themesMutex.RLock()
+	defer themesMutex.RUnlock()
+	theme, ok = themes[strings.ToLower(name)]
+	return
+}
+
+// LoadTheme loads a theme from config files using swap.
+func LoadTheme(configFiles ...string) (*Theme, error) {
+	theme := &Theme{}
+	if err := swap.Parse(theme, configFiles...); err != nil {
+		return nil, fmt.Errorf("can't load theme: %v", err)
+	}
+	return theme, nil
+}
+
+// themeOrDefault returns theme or, if nil, the DefaultTheme.
+func themeOrDefault(theme *Theme) *Theme {
+	if theme == nil {
+		return DefaultTheme
+	}
+	return theme
+}
+
+// resolveTheme returns the theme by name or loads it
+// if name is a config file path, an empty name returns the DefaultTheme.
+func resolveTheme(name string) (*Theme, error) {
+	if len(name) == 0 {
+		return DefaultTheme, nil
+	}
+	if theme, ok := ThemeByName(name); ok {
+		return theme, nil
+	}
+	return LoadTheme(name)
+}
+
+// LevelColor returns the color of the given logrus level.
+func (t *Theme) LevelColor(level logrus.Level) Color {
+	switch level {
+	case logrus.TraceLevel:
+		return t.Trace
+	case logrus.DebugLevel:
+		return t.Debug
+	case logrus.InfoLevel:
+		return t.Info
+	case logrus.WarnLevel:
+		return t.Warn
+	case logrus.ErrorLevel:
+		return t.Error
+	case logrus.FatalLevel:
+		return t.Fatal
+	case logrus.PanicLevel:
+		return t.Panic
+	default:
+		return ""
+	}
+}
+
+// MethodColor returns the color of the given http method.
+func (t *Theme) MethodColor(method string) Color {
+	return t.Methods[strings.ToUpper(method)]
+}
+
+// StatusColor returns the color of the given http status code.
+func (t *Theme) StatusColor(statusCode int) Color {
+	switch {
+	case statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices: // 200 300
+		return t.Status2xx
+	case statusCode >= http.StatusMultipleChoices && statusCode < http.StatusBadRequest: // redirects... 300 400
+		return t.Status3xx
+	case statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError: // client errors... 400 500
+		return t.Status4xx
+	default: // server error or unknown
+		return t.Status5xx
+	}
+}
@@ -0,0 +1,68 @@
+package ansilog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTheme(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ansilog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "theme.yml")
+	data := "name: custom\ninfo: light-blue\nerror: bold red\nmethods:\n  GET: green\n"
+	if err = ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := resolveTheme(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if theme.Info != ColorLightBlue || theme.Error != "1;31m" || theme.MethodColor("get") != ColorGreen {
+		t.Errorf("unexpected theme: %+v", theme)
+	}
+}
+
+func TestLoggerTheme(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, err := NewWithConfig(Config{Out: out, ColorsMode: ConsoleColorsModeEnabled, Theme: "monochrome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.WithField("key", "value").Error("message")
+	if !strings.HasPrefix(out.String(), "\033[1mERROR\033[0m") || !strings.Contains(out.String(), "\033[1mkey\033[0m=value") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestLoggerTheme_FieldMap(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, err := NewWithConfig(Config{
+		Out:        out,
+		ColorsMode: ConsoleColorsModeEnabled,
+		Theme:      "monochrome",
+		Formatter: FormatterConfig{
+			DisableTimestamp: true,
+			FieldMap:         map[string]string{"msg": "message", "level": "severity"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.WithField("key", "").Info("hi")
+	want := "\033[1mseverity\033[0m=INFO \033[1mmessage\033[0m=hi \033[1mkey\033[0m=\"\"\n"
+	if got := out.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+package ansilog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Trace propagation headers.
+const (
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+
+	HeaderB3           = "b3"
+	HeaderB3TraceID    = "X-B3-TraceId"
+	HeaderB3SpanID     = "X-B3-SpanId"
+	HeaderB3ParentSpan = "X-B3-ParentSpanId"
+	HeaderB3Sampled    = "X-B3-Sampled"
+	HeaderB3Flags      = "X-B3-Flags"
+)
+
+// SpanContext identifies a span of a distributed trace,
+// see https://www.w3.org/TR/trace-context/ and https://github.com/openzipkin/b3-propagation.
+type SpanContext struct {
+	// TraceID is the 32 hex characters trace id.
+	TraceID string
+	// SpanID is the 16 hex characters id of the current span.
+	SpanID string
+	// ParentSpanID is the id of the caller span, if any.
+	ParentSpanID string
+	// Sampled reports whether the trace is recorded.
+	Sampled bool
+	// TraceState is the vendor specific W3C tracestate, propagated as is.
+	TraceState string
+}
+
+// NewSpanContext starts a new sampled trace.
+func NewSpanContext() SpanContext {
+	return SpanContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Sampled: true,
+	}
+}
+
+// IsValid reports whether the trace and span ids are valid.
+func (sc SpanContext) IsValid() bool {
+	return validHexID(sc.TraceID, 32) && validHexID(sc.SpanID, 16)
+}
+
+// Child returns a new span of the same trace, sc becomes its parent.
+func (sc SpanContext) Child() SpanContext {
+	return SpanContext{
+		TraceID:      sc.TraceID,
+		SpanID:       randomHex(8),
+		ParentSpanID: sc.SpanID,
+		Sampled:      sc.Sampled,
+		TraceState:   sc.TraceState,
+	}
+}
+
+// TraceParent returns the W3C traceparent header value, eg.:
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID + "-" + sc.SpanID + "-" + flags
+}
+
+// B3 returns the B3 single header value, eg.:
+// "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1".
+func (sc SpanContext) B3() string {
+	sampled := "0"
+	if sc.Sampled {
+		sampled = "1"
+	}
+	b3 := sc.TraceID + "-" + sc.SpanID + "-" + sampled
+	if len(sc.ParentSpanID) > 0 {
+		b3 += "-" + sc.ParentSpanID
+	}
+	return b3
+}
+
+// ContextWithSpanContext returns a copy of ctx carrying the span context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextContextKey, sc)
+}
+
+// SpanContextFromContext returns the span context stored in ctx by the HttpTracer middlewares.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanContextContextKey).(SpanContext)
+	return sc, ok
+}
+
+// ExtractSpanContext parses the W3C Trace Context headers or,
+// if missing, the B3 single or multi headers.
+func ExtractSpanContext(header http.Header) (SpanContext, bool) {
+	if sc, ok := parseTraceParent(header.Get(HeaderTraceParent)); ok {
+		sc.TraceState = header.Get(HeaderTraceState)
+		return sc, true
+	}
+	if sc, ok := parseB3(header.Get(HeaderB3)); ok {
+		return sc, true
+	}
+	return parseB3Multi(header)
+}
+
+// parseTraceParent parses "version-traceid-spanid-flags".
+func parseTraceParent(value string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[3]) != 2 {
+		return sc, false
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+
+	sc = SpanContext{TraceID: parts[1], SpanID: parts[2], Sampled: flags[0]&1 == 1}
+	return sc, sc.IsValid()
+}
+
+// parseB3 parses the B3 single header "traceid-spanid-sampled-parentspanid",
+// a sampling only header ("0", "1" or "d") carries no ids and is ignored.
+func parseB3(value string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return sc, false
+	}
+
+	sc = SpanContext{TraceID: padTraceID(parts[0]), SpanID: strings.ToLower(parts[1]), Sampled: true}
+	if len(parts) > 2 {
+		sc.Sampled = parts[2] == "1" || parts[2] == "d"
+	}
+	if len(parts) > 3 {
+		sc.ParentSpanID = strings.ToLower(parts[3])
+	}
+	return sc, sc.IsValid()
+}
+
+// parseB3Multi parses the X-B3-* headers.
+func parseB3Multi(header http.Header) (sc SpanContext, ok bool) {
+	traceID := header.Get(HeaderB3TraceID)
+	if len(traceID) == 0 {
+		return sc, false
+	}
+
+	sampled := header.Get(HeaderB3Sampled)
+	sc = SpanContext{
+		TraceID:      padTraceID(traceID),
+		SpanID:       strings.ToLower(header.Get(HeaderB3SpanID)),
+		ParentSpanID: strings.ToLower(header.Get(HeaderB3ParentSpan)),
+		Sampled:      sampled != "0" && sampled != "false" || header.Get(HeaderB3Flags) == "1",
+	}
+	return sc, sc.IsValid()
+}
+
+// padTraceID left pads 64 bit B3 trace ids to 128 bit.
+func padTraceID(id string) string {
+	id = strings.ToLower(id)
+	if len(id) == 16 {
+		return strings.Repeat("0", 16) + id
+	}
+	return id
+}
+
+// validHexID reports whether id is a non zero lower case hex string of the given length.
+func validHexID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
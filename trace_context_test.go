@@ -0,0 +1,136 @@
+package ansilog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExtractSpanContext(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		ok     bool
+		want   SpanContext
+	}{
+		{"none", nil, false, SpanContext{}},
+		{
+			"traceparent",
+			map[string]string{HeaderTraceParent: "00-" + traceID + "-" + spanID + "-01", HeaderTraceState: "congo=t61rcWkgMzE"},
+			true, SpanContext{TraceID: traceID, SpanID: spanID, Sampled: true, TraceState: "congo=t61rcWkgMzE"},
+		},
+		{
+			"traceparent not sampled",
+			map[string]string{HeaderTraceParent: "00-" + traceID + "-" + spanID + "-00"},
+			true, SpanContext{TraceID: traceID, SpanID: spanID},
+		},
+		{"traceparent invalid version", map[string]string{HeaderTraceParent: "ff-" + traceID + "-" + spanID + "-01"}, false, SpanContext{}},
+		{"traceparent zero trace id", map[string]string{HeaderTraceParent: "00-" + strings.Repeat("0", 32) + "-" + spanID + "-01"}, false, SpanContext{}},
+		{"traceparent upper case", map[string]string{HeaderTraceParent: "00-" + strings.ToUpper(traceID) + "-" + spanID + "-01"}, false, SpanContext{}},
+		{
+			"traceparent future version",
+			map[string]string{HeaderTraceParent: "01-" + traceID + "-" + spanID + "-01-extra"},
+			true, SpanContext{TraceID: traceID, SpanID: spanID, Sampled: true},
+		},
+		{
+			"b3 single",
+			map[string]string{HeaderB3: traceID + "-" + spanID + "-d-" + "05e3ac9a4f6e3b90"},
+			true, SpanContext{TraceID: traceID, SpanID: spanID, ParentSpanID: "05e3ac9a4f6e3b90", Sampled: true},
+		},
+		{
+			"b3 single 64 bit",
+			map[string]string{HeaderB3: "a3ce929d0e0e4736-" + spanID + "-0"},
+			true, SpanContext{TraceID: "0000000000000000a3ce929d0e0e4736", SpanID: spanID},
+		},
+		{"b3 sampling only", map[string]string{HeaderB3: "1"}, false, SpanContext{}},
+		{
+			"b3 multi",
+			map[string]string{HeaderB3TraceID: traceID, HeaderB3SpanID: spanID, HeaderB3Sampled: "1"},
+			true, SpanContext{TraceID: traceID, SpanID: spanID, Sampled: true},
+		},
+		{
+			"traceparent wins",
+			map[string]string{HeaderTraceParent: "00-" + traceID + "-" + spanID + "-01", HeaderB3: "a3ce929d0e0e4736-05e3ac9a4f6e3b90"},
+			true, SpanContext{TraceID: traceID, SpanID: spanID, Sampled: true},
+		},
+	}
+
+	for _, test := range tests {
+		header := http.Header{}
+		for k, v := range test.header {
+			header.Set(k, v)
+		}
+
+		got, ok := ExtractSpanContext(header)
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("%s: got %+v, %v", test.name, got, ok)
+		}
+	}
+}
+
+func TestSpanContext_Headers(t *testing.T) {
+	sc := NewSpanContext()
+	if !sc.IsValid() || !sc.Sampled {
+		t.Fatalf("invalid span context: %+v", sc)
+	}
+
+	child := sc.Child()
+	if child.TraceID != sc.TraceID || child.ParentSpanID != sc.SpanID || child.SpanID == sc.SpanID {
+		t.Errorf("invalid child: %+v", child)
+	}
+
+	if got, ok := parseTraceParent(child.TraceParent()); !ok || got.TraceID != child.TraceID || got.SpanID != child.SpanID {
+		t.Errorf("traceparent round trip: %+v", got)
+	}
+	if got, ok := parseB3(child.B3()); !ok || got != child.withoutState() {
+		t.Errorf("b3 round trip: %+v", got)
+	}
+}
+
+func (sc SpanContext) withoutState() SpanContext {
+	sc.TraceState = ""
+	return sc
+}
+
+func TestHttpTracer_TraceContext(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger, err := NewWithConfig(Config{Out: out, Formatter: FormatterConfig{Name: FormatterJSON}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sc SpanContext
+	tracer := NewHttpTracerWithOptions(WithLogger(logger))
+	handler := tracer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, _ = SpanContextFromContext(r.Context())
+		logger.WithContext(r.Context()).Info("handling")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if sc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.ParentSpanID != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected span context: %+v", sc)
+	}
+	if strings.Count(out.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) != 2 ||
+		strings.Count(out.String(), `"span_id":"`+sc.SpanID+`"`) != 2 {
+		t.Errorf("expected the trace ids in both entries, got: %q", out.String())
+	}
+
+	// new trace
+	out.Reset()
+	tpl := NewHttpTracerWithOptions(WithWriter(out), WithTemplateText("{{.TraceID}} {{.SpanID}}"))
+	tpl.Handler(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	parts := strings.Fields(out.String())
+	if len(parts) != 2 || !validHexID(parts[0], 32) || !validHexID(parts[1], 16) {
+		t.Errorf("unexpected ids: %q", out.String())
+	}
+}